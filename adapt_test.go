@@ -1,8 +1,12 @@
 package adapt
 
 import (
+	"errors"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func ensureFileIsDeleted(filename string) {
@@ -101,6 +105,13 @@ func TestMigrate_Error(t *testing.T) {
 		{"Invalid MemorySource (only down)", args{SourceCollection{NewMemoryFSSource(map[string]string{
 			"init.down.sql": "DROP DATABASE test_db",
 		})}}},
+		{"Invalid MemorySource (combined without markers)", args{SourceCollection{NewMemoryFSSource(map[string]string{
+			"init.sql": "CREATE DATABASE test_db",
+		})}}},
+		{"Invalid MemorySource (combined and separate)", args{SourceCollection{NewMemoryFSSource(map[string]string{
+			"init.sql":    "-- +adapt Up\nCREATE DATABASE test_db",
+			"init.up.sql": "CREATE DATABASE test_db",
+		})}}},
 	}
 
 	for _, tt := range tests {
@@ -119,3 +130,643 @@ func TestMigrate_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrate_HookFingerprint(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	migrate := func(fingerprint string, options ...Option) error {
+		return Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{
+			NewCodeSource("20240101_1200_hook", Hook{
+				MigrateUp:   func() error { return nil },
+				Fingerprint: fingerprint,
+			}),
+		}, options...)
+	}
+
+	if err := migrate("v1"); err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+
+	listed, err := NewFileDriver(filename).ListMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listed[0].Hash == nil || *listed[0].Hash != *(Hook{Fingerprint: "v1"}).Hash() {
+		t.Errorf("stored hash = %v, want hash of fingerprint", listed[0].Hash)
+	}
+
+	if err = migrate("v1"); err != nil {
+		t.Errorf("Migrate() unchanged fingerprint error = %v", err)
+	}
+	if err = migrate("v2"); !errors.Is(err, ErrIntegrityProtection) {
+		t.Errorf("Migrate() changed fingerprint error = %v, want %v", err, ErrIntegrityProtection)
+	}
+	if err = migrate("v2", OnHookChange(HookChangeWarn)); err != nil {
+		t.Errorf("Migrate() changed fingerprint with warn policy error = %v", err)
+	}
+
+	// the accepted change is stored, so it is only reported once
+	if err = migrate("v2"); err != nil {
+		t.Errorf("Migrate() after accepted change error = %v", err)
+	}
+	if err = migrate(""); !errors.Is(err, ErrIntegrityProtection) {
+		t.Errorf("Migrate() removed fingerprint error = %v, want %v", err, ErrIntegrityProtection)
+	}
+}
+
+func TestMigrate_HookFingerprintAdded(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	migrate := func(fingerprint string) error {
+		return Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{
+			NewCodeSource("20240101_1200_hook", Hook{
+				MigrateUp:   func() error { return nil },
+				Fingerprint: fingerprint,
+			}),
+		}, DisableLogger())
+	}
+	storedHash := func() *string {
+		t.Helper()
+		listed, err := NewFileDriver(filename).ListMigrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return listed[0].Hash
+	}
+
+	if err := migrate(""); err != nil {
+		t.Fatalf("Migrate() without fingerprint error = %v", err)
+	}
+	if hash := storedHash(); hash != nil {
+		t.Fatalf("stored hash = %v, want none", *hash)
+	}
+
+	// the fingerprint added later is stored, so the hook is protected from now on
+	if err := migrate("v1"); err != nil {
+		t.Fatalf("Migrate() with added fingerprint error = %v", err)
+	}
+	if hash := storedHash(); hash == nil || *hash != *(Hook{Fingerprint: "v1"}).Hash() {
+		t.Errorf("stored hash = %v, want hash of fingerprint", hash)
+	}
+	if err := migrate("v2"); !errors.Is(err, ErrIntegrityProtection) {
+		t.Errorf("Migrate() changed fingerprint error = %v, want %v", err, ErrIntegrityProtection)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	sources := func(fingerprint string) SourceCollection {
+		return SourceCollection{
+			NewCodeSource("20240101_1200_hook", Hook{
+				MigrateUp:   func() error { return nil },
+				Fingerprint: fingerprint,
+			}),
+		}
+	}
+
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), sources("v1"))
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+
+	// add a migration that started, but never finished
+	err = NewFileDriver(filename).AddMigration(&Migration{ID: "20240102_1200_crashed", Started: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Repair(NewFileDriver(filename), sources("v2"), RepairHashes()); err == nil {
+		t.Errorf("Repair() expected error without executor and reason")
+	}
+
+	entries, err := Repair(NewFileDriver(filename), sources("v2"),
+		RepairBy("jane.doe"),
+		RepairReason("hook refactoring without behaviour change"),
+		RepairHashes(),
+		RepairDeleteUnfinished(),
+	)
+	if err != nil {
+		t.Fatalf("Repair() unexpected error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Kind != JournalRepairHash || entries[1].Kind != JournalRepairDeleted {
+		t.Errorf("Repair() entries = %v", entries)
+	}
+
+	journal, err := NewFileDriver(filename).(DriverJournal).ListJournalEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal) != 2 || journal[0].Executor != "jane.doe" || journal[0].Reason != "hook refactoring without behaviour change" {
+		t.Errorf("ListJournalEntries() = %v", journal)
+	}
+
+	if err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), sources("v2")); err != nil {
+		t.Errorf("Migrate() after repair error = %v", err)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	var executed []string
+	sources := SourceCollection{
+		NewCodePackageSource(map[string]Hook{
+			"20240101_1200_legacy-tables": {MigrateUp: func() error {
+				executed = append(executed, "20240101_1200_legacy-tables")
+				return nil
+			}},
+			"20240102_1200_legacy-indexes": {MigrateUp: func() error {
+				executed = append(executed, "20240102_1200_legacy-indexes")
+				return nil
+			}},
+			"20240301_1200_new-feature": {MigrateUp: func() error {
+				executed = append(executed, "20240301_1200_new-feature")
+				return nil
+			}},
+		}),
+	}
+
+	tests := []struct {
+		name    string
+		run     func(filename string) error
+		wantErr bool
+	}{
+		{"Baseline", func(filename string) error {
+			if err := Baseline(NewFileDriver(filename), sources, "20240102_1200_legacy-indexes"); err != nil {
+				return err
+			}
+			return Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), sources)
+		}, false},
+		{"BaselineOnEmptyMeta", func(filename string) error {
+			return Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), sources,
+				BaselineOnEmptyMeta("20240102_1200_legacy-indexes"))
+		}, false},
+		{"Baseline unknown id", func(filename string) error {
+			return Baseline(NewFileDriver(filename), sources, "20240103_1200_unknown")
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := "test.json"
+			ensureFileIsDeleted(filename)
+			defer ensureFileIsDeleted(filename)
+			executed = nil
+
+			err := tt.run(filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(executed) != 1 || executed[0] != "20240301_1200_new-feature" {
+				t.Errorf("executed = %v, want only new-feature", executed)
+			}
+
+			listed, err := NewFileDriver(filename).ListMigrations()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed) != 3 || !listed[0].IsBaseline() || !listed[1].IsBaseline() || listed[2].IsBaseline() {
+				t.Errorf("listed = %v, want first two migrations as baseline", listed)
+			}
+			if listed[0].Finished == nil {
+				t.Errorf("baseline migration not finished")
+			}
+		})
+	}
+}
+
+func TestMigrate_OrderBy(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	var executed []string
+	hooks := func(ids ...string) SourceCollection {
+		pkg := make(map[string]Hook)
+		for _, id := range ids {
+			id := id
+			pkg[id] = Hook{MigrateUp: func() error {
+				executed = append(executed, id)
+				return nil
+			}}
+		}
+		return SourceCollection{NewCodePackageSource(pkg)}
+	}
+
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), hooks("V1__init", "V2__users", "V10__index"),
+		OrderBy(FlywayOrder), ValidateIDs(ValidFlywayID))
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+	if want := []string{"V1__init", "V2__users", "V10__index"}; !reflect.DeepEqual(executed, want) {
+		t.Errorf("executed = %v, want %v", executed, want)
+	}
+
+	// applied migrations are reported in lexical order by the driver, but must
+	// still be recognized as known and in order
+	executed = nil
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), hooks("V1__init", "V2__users", "V10__index", "V11__more"),
+		OrderBy(FlywayOrder), ValidateIDs(ValidFlywayID))
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+	if want := []string{"V11__more"}; !reflect.DeepEqual(executed, want) {
+		t.Errorf("executed = %v, want %v", executed, want)
+	}
+
+	executed = nil
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), hooks("V1__init", "V2__users", "V10__index", "V11__more", "add_column"),
+		OrderBy(FlywayOrder), ValidateIDs(ValidFlywayID))
+	if !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("Migrate() error = %v, want %v", err, ErrInvalidSource)
+	}
+	if len(executed) > 0 {
+		t.Errorf("executed = %v, want nothing", executed)
+	}
+}
+
+func TestMigrate_DependsOn(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	var executed []string
+	hook := func(id string, dependsOn ...string) Hook {
+		return Hook{
+			MigrateUp: func() error {
+				executed = append(executed, id)
+				return nil
+			},
+			DependsOn: dependsOn,
+		}
+	}
+
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_init":  hook("1_init"),
+		"3_users": hook("3_users"),
+	})})
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+
+	// a branch migration fills the hole at "2", but depends on "4", which was
+	// merged later. It must only be applied after "4".
+	executed = nil
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_init":   hook("1_init"),
+		"2_branch": hook("2_branch", "4_roles"),
+		"3_users":  hook("3_users"),
+		"4_roles":  hook("4_roles", "3_users"),
+	})})
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+	if want := []string{"4_roles", "2_branch"}; !reflect.DeepEqual(executed, want) {
+		t.Errorf("executed = %v, want %v", executed, want)
+	}
+
+	// dependencies that are neither available nor applied are refused
+	executed = nil
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_init":    hook("1_init"),
+		"2_branch":  hook("2_branch", "4_roles"),
+		"3_users":   hook("3_users"),
+		"4_roles":   hook("4_roles", "3_users"),
+		"5_reports": hook("5_reports", "0_missing"),
+	})})
+	if !errors.Is(err, ErrIntegrityProtection) {
+		t.Errorf("Migrate() error = %v, want %v", err, ErrIntegrityProtection)
+	}
+	if len(executed) > 0 {
+		t.Errorf("executed = %v, want nothing", executed)
+	}
+
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_init":   hook("1_init"),
+		"2_branch": hook("2_branch", "4_roles"),
+		"3_users":  hook("3_users"),
+		"4_roles":  hook("4_roles", "2_branch"),
+	})})
+	if !errors.Is(err, ErrInvalidSource) {
+		t.Errorf("Migrate() error = %v, want %v", err, ErrInvalidSource)
+	}
+}
+
+func TestMigrate_StrictOrdering(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	var executed []string
+	hooks := func(ids ...string) SourceCollection {
+		pkg := make(map[string]Hook)
+		for _, id := range ids {
+			id := id
+			pkg[id] = Hook{MigrateUp: func() error {
+				executed = append(executed, id)
+				return nil
+			}}
+		}
+		return SourceCollection{NewCodePackageSource(pkg)}
+	}
+
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), hooks("1_init", "3_users"), StrictOrdering())
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+
+	executed = nil
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), hooks("1_init", "2_branch", "2_other-branch", "3_users", "4_roles"), StrictOrdering())
+	if !errors.Is(err, ErrIntegrityProtection) {
+		t.Fatalf("Migrate() error = %v, want %v", err, ErrIntegrityProtection)
+	}
+	if !strings.Contains(err.Error(), "2_branch, 2_other-branch") {
+		t.Errorf("Migrate() error = %v, want offending ids listed", err)
+	}
+	if len(executed) > 0 {
+		t.Errorf("executed = %v, want nothing", executed)
+	}
+}
+
+func TestMigrate_Tags(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	var executed []string
+	hook := func(id string, tags ...string) Hook {
+		return Hook{
+			MigrateUp: func() error {
+				executed = append(executed, id)
+				return nil
+			},
+			Tags: tags,
+		}
+	}
+	sources := SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_init":      hook("1_init"),
+		"2_seed":      hook("2_seed", "dev", "seed"),
+		"3_users":     hook("3_users"),
+		"4_eu-region": hook("4_eu-region", "eu"),
+	})}
+	states := func(options ...Option) map[string]StatusState {
+		statuses, err := Status(NewFileDriver(filename), sources, options...)
+		if err != nil {
+			t.Fatalf("Status() unexpected error = %v", err)
+		}
+		got := make(map[string]StatusState)
+		for _, s := range statuses {
+			got[s.ID] = s.State
+		}
+		return got
+	}
+
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), sources, ExcludeTags("seed"), IncludeTags("us"))
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+	if want := []string{"1_init", "3_users"}; !reflect.DeepEqual(executed, want) {
+		t.Errorf("executed = %v, want %v", executed, want)
+	}
+	if got, want := states(ExcludeTags("seed"), IncludeTags("us")), map[string]StatusState{
+		"1_init":      StatusApplied,
+		"2_seed":      StatusSkipped,
+		"3_users":     StatusApplied,
+		"4_eu-region": StatusSkipped,
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Status() = %v, want %v", got, want)
+	}
+
+	// skipped migrations aren't holes and are applied once they match the filter
+	executed = nil
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), sources, IncludeTags("eu"))
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+	if want := []string{"4_eu-region"}; !reflect.DeepEqual(executed, want) {
+		t.Errorf("executed = %v, want %v", executed, want)
+	}
+
+	// applied migrations filtered by their tags are still known
+	executed = nil
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), sources, ExcludeTags("eu", "seed"))
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+	if len(executed) > 0 {
+		t.Errorf("executed = %v, want nothing", executed)
+	}
+	if got, want := states(ExcludeTags("eu", "seed")), map[string]StatusState{
+		"1_init":      StatusApplied,
+		"2_seed":      StatusSkipped,
+		"3_users":     StatusApplied,
+		"4_eu-region": StatusApplied,
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Status() = %v, want %v", got, want)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	noop := Hook{MigrateUp: func() error { return nil }}
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_init": noop,
+		"2_gone": noop,
+	})})
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+
+	statuses, err := Status(NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_init":  noop,
+		"3_users": noop,
+	})})
+	if err != nil {
+		t.Fatalf("Status() unexpected error = %v", err)
+	}
+
+	var got []string
+	for _, s := range statuses {
+		got = append(got, s.ID+"="+string(s.State))
+		if (s.Applied == nil) != (s.State == StatusPending) || (s.Available == nil) != (s.State == StatusUnknown) {
+			t.Errorf("Status() %s has unexpected Applied/Available fields", s.ID)
+		}
+	}
+	if want := []string{"1_init=applied", "2_gone=unknown", "3_users=pending"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Status() = %v, want %v", got, want)
+	}
+}
+
+func TestMigrate_Repeatable(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	var executed []string
+	hook := func(id string, fingerprint string) Hook {
+		return Hook{
+			MigrateUp: func() error {
+				executed = append(executed, id)
+				return nil
+			},
+			Fingerprint: fingerprint,
+		}
+	}
+	sources := func(viewsFingerprint string, grantsFingerprint string, ids ...string) SourceCollection {
+		pkg := map[string]Hook{
+			"R__views":  hook("R__views", viewsFingerprint),
+			"9_grants":  hook("9_grants", grantsFingerprint),
+			"1_init":    hook("1_init", ""),
+			"2_columns": hook("2_columns", ""),
+		}
+		grants := pkg["9_grants"]
+		grants.Repeatable = true
+		pkg["9_grants"] = grants
+		for _, id := range ids {
+			pkg[id] = hook(id, "")
+		}
+		return SourceCollection{NewCodePackageSource(pkg)}
+	}
+
+	tests := []struct {
+		name         string
+		sources      SourceCollection
+		wantExecuted []string
+	}{
+		{"first run after versioned migrations", sources("v1", "v1"), []string{"1_init", "2_columns", "9_grants", "R__views"}},
+		{"unchanged", sources("v1", "v1"), nil},
+		{"changed content", sources("v2", "v1"), []string{"R__views"}},
+		{"changed content with new versioned migration", sources("v2", "v2", "3_users"), []string{"3_users", "9_grants"}},
+	}
+	for _, tt := range tests {
+		executed = nil
+		err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), tt.sources)
+		if err != nil {
+			t.Fatalf("%s: Migrate() unexpected error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(executed, tt.wantExecuted) {
+			t.Errorf("%s: executed = %v, want %v", tt.name, executed, tt.wantExecuted)
+		}
+	}
+
+	journal, err := NewFileDriver(filename).(DriverJournal).ListJournalEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs []string
+	for _, entry := range journal {
+		if entry.Kind == JournalRepeatableRun {
+			runs = append(runs, entry.MigrationID+": "+entry.Reason)
+		}
+	}
+	if want := []string{"9_grants: first run", "R__views: first run", "R__views: content changed", "9_grants: content changed"}; !reflect.DeepEqual(runs, want) {
+		t.Errorf("journal runs = %v, want %v", runs, want)
+	}
+
+	statuses, err := Status(NewFileDriver(filename), sources("v3", "v2", "3_users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		want := StatusApplied
+		if s.ID == "R__views" {
+			want = StatusPending
+		}
+		if s.State != want {
+			t.Errorf("Status() %s = %s, want %s", s.ID, s.State, want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	down := &ParsedMigration{UseTx: true, Stmts: []string{"DROP TABLE b;"}}
+	hook := Hook{MigrateUp: func() error { return nil }, MigrateDown: func() *ParsedMigration { return down }}
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_a": hook,
+		"3_b": hook,
+	})})
+	if err != nil {
+		t.Fatalf("Migrate() unexpected error = %v", err)
+	}
+
+	plan, err := Plan(NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_a": hook,
+		"2_c": hook,
+		"4_d": hook,
+	})})
+	if err != nil {
+		t.Fatalf("Plan() unexpected error = %v", err)
+	}
+
+	var rollback, apply []string
+	for _, m := range plan.Rollback {
+		rollback = append(rollback, m.ID)
+	}
+	for _, m := range plan.Apply {
+		apply = append(apply, m.ID)
+	}
+	if !reflect.DeepEqual(rollback, []string{"3_b"}) || !reflect.DeepEqual(apply, []string{"2_c", "4_d"}) {
+		t.Errorf("Plan() rollback = %v, apply = %v", rollback, apply)
+	}
+
+	// planning never changes anything
+	statuses, err := Status(NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{"1_a": hook})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[1].ID != "3_b" || statuses[1].State != StatusUnknown {
+		t.Errorf("Status() after Plan() = %v", statuses)
+	}
+}
+
+func TestPlan_BaselineOnEmptyMeta(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	noop := Hook{MigrateUp: func() error { return nil }}
+	sources := SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_a": noop,
+		"2_b": noop,
+		"3_c": noop,
+	})}
+
+	plan, err := Plan(NewFileDriver(filename), sources, BaselineOnEmptyMeta("2_b"), DisableLogger())
+	if err != nil {
+		t.Fatalf("Plan() unexpected error = %v", err)
+	}
+
+	var apply []string
+	for _, am := range plan.Apply {
+		apply = append(apply, am.ID)
+	}
+	if len(plan.Rollback) > 0 || !reflect.DeepEqual(apply, []string{"3_c"}) {
+		t.Errorf("Plan() rollback = %v, apply = %v, want apply [3_c]", plan.Rollback, apply)
+	}
+
+	// planning doesn't record the baseline
+	statuses, err := Status(NewFileDriver(filename), sources, DisableLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.Applied != nil {
+			t.Errorf("Status() after Plan() reports %s as stored", s.ID)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+/*
+Package fakesql implements a fake database/sql/driver that doesn't execute
+anything. Instead, it records every executed statement, query and transaction
+operation and allows tests to inject errors:
+
+	db, rec := fakesql.Open()
+	rec.FailStatement(2, errors.New("boom"))
+
+	// use db like any other *sql.DB and assert rec.Events() afterwards
+
+The package doesn't depend on adapt itself, so it can be used by adapt's own
+tests as well as by tests of applications and custom drivers.
+*/
+package fakesql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Kind classifies an Event
+type Kind string
+
+const (
+	// KindExec is recorded for every executed statement
+	KindExec Kind = "exec"
+	// KindQuery is recorded for every executed query
+	KindQuery Kind = "query"
+	// KindBegin is recorded when a transaction is started
+	KindBegin Kind = "begin"
+	// KindCommit is recorded when a transaction is committed
+	KindCommit Kind = "commit"
+	// KindRollback is recorded when a transaction is rolled back
+	KindRollback Kind = "rollback"
+)
+
+// Event is a single operation recorded by a Recorder
+type Event struct {
+	Kind Kind
+	// Query is the statement or query. It is empty for transaction operations.
+	Query string
+	// Args are the arguments passed along with Query
+	Args []driver.Value
+	// InTx reports whether the operation was performed inside a transaction
+	InTx bool
+	// Err is the error returned to database/sql, e.g. an injected failure
+	Err error
+}
+
+// String returns the Kind, followed by the Query for statements and queries
+func (e Event) String() string {
+	if len(e.Query) == 0 {
+		return string(e.Kind)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Query)
+}
+
+// ExecFunc is called for every executed statement. A returned error fails the
+// statement.
+type ExecFunc func(query string, args []driver.Value) error
+
+// QueryFunc is called for every executed query and provides its result set.
+// Every row must contain one value per column.
+type QueryFunc func(query string, args []driver.Value) (columns []string, rows [][]driver.Value, err error)
+
+// TxFunc is called whenever a transaction ends. committed reports whether the
+// transaction was committed successfully.
+type TxFunc func(committed bool)
+
+// Recorder records all operations performed on the *sql.DB returned by Open
+// and controls their outcome. It is safe for concurrent use.
+type Recorder struct {
+	mu         sync.Mutex
+	events     []Event
+	statements int
+	failures   map[int]error
+	failBegin  error
+	failCommit error
+	onExec     ExecFunc
+	onQuery    QueryFunc
+	onTxEnd    []TxFunc
+}
+
+// Open returns a new *sql.DB backed by the fake driver and the Recorder
+// observing it. By default, every statement succeeds and every query returns
+// an empty result set.
+func Open() (*sql.DB, *Recorder) {
+	r := &Recorder{
+		failures: make(map[int]error),
+	}
+	return sql.OpenDB(&connector{r: r}), r
+}
+
+// Events returns all recorded operations in the order they were performed
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+// Statements returns the queries of all recorded KindExec events, including
+// failed ones
+func (r *Recorder) Statements() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var statements []string
+	for _, e := range r.events {
+		if e.Kind == KindExec {
+			statements = append(statements, e.Query)
+		}
+	}
+	return statements
+}
+
+// Reset removes all recorded events and restarts the statement count used by
+// FailStatement. Injected failures and handlers are kept.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = nil
+	r.statements = 0
+}
+
+// FailStatement lets the n-th executed statement (starting at 1) fail with
+// err. Only statements are counted, queries and transaction operations are
+// not.
+func (r *Recorder) FailStatement(n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[n] = err
+}
+
+// FailBegin lets every following attempt to start a transaction fail with err.
+// Pass nil to stop failing.
+func (r *Recorder) FailBegin(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failBegin = err
+}
+
+// FailCommit lets every following commit fail with err. Pass nil to stop
+// failing.
+func (r *Recorder) FailCommit(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failCommit = err
+}
+
+// OnExec registers fn, which is called for every statement that isn't failed
+// by FailStatement. It can be used to simulate state or fail statements by
+// their content.
+func (r *Recorder) OnExec(fn ExecFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onExec = fn
+}
+
+// OnQuery registers fn, which provides the result sets of all queries
+func (r *Recorder) OnQuery(fn QueryFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onQuery = fn
+}
+
+// OnTxEnd registers fn, which is called after every commit and rollback. It can
+// be used to apply or discard state staged by a transaction. In contrast to
+// OnExec and OnQuery, multiple calls add up and all handlers are called in the
+// order they were registered.
+func (r *Recorder) OnTxEnd(fn TxFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onTxEnd = append(r.onTxEnd, fn)
+}
+
+func (r *Recorder) exec(query string, args []driver.Value, inTx bool) error {
+	r.mu.Lock()
+	r.statements++
+	err, fail := r.failures[r.statements]
+	onExec := r.onExec
+	r.mu.Unlock()
+
+	// handlers are called without holding the lock, so they can use the
+	// Recorder themselves
+	if !fail && onExec != nil {
+		err = onExec(query, args)
+	}
+
+	r.record(Event{Kind: KindExec, Query: query, Args: args, InTx: inTx, Err: err})
+	return err
+}
+
+func (r *Recorder) query(query string, args []driver.Value, inTx bool) (driver.Rows, error) {
+	r.mu.Lock()
+	onQuery := r.onQuery
+	r.mu.Unlock()
+
+	res := &rows{}
+	var err error
+	if onQuery != nil {
+		res.columns, res.values, err = onQuery(query, args)
+	}
+
+	r.record(Event{Kind: KindQuery, Query: query, Args: args, InTx: inTx, Err: err})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *Recorder) begin() error {
+	r.mu.Lock()
+	err := r.failBegin
+	r.mu.Unlock()
+
+	r.record(Event{Kind: KindBegin, Err: err})
+	return err
+}
+
+func (r *Recorder) commit() error {
+	r.mu.Lock()
+	err := r.failCommit
+	r.mu.Unlock()
+
+	r.record(Event{Kind: KindCommit, InTx: true, Err: err})
+	r.txEnd(err == nil)
+	return err
+}
+
+func (r *Recorder) rollback() {
+	r.record(Event{Kind: KindRollback, InTx: true})
+	r.txEnd(false)
+}
+
+func (r *Recorder) txEnd(committed bool) {
+	r.mu.Lock()
+	handlers := make([]TxFunc, len(r.onTxEnd))
+	copy(handlers, r.onTxEnd)
+	r.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(committed)
+	}
+}
+
+func (r *Recorder) record(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+type connector struct {
+	r *Recorder
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{r: c.r}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return fakeDriver{c: c}
+}
+
+type fakeDriver struct {
+	c *connector
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return d.c.Connect(context.Background())
+}
+
+type conn struct {
+	r    *Recorder
+	inTx bool
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if c.inTx {
+		return nil, errors.New("fakesql: transaction already started on connection")
+	}
+	if err := c.r.begin(); err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &tx{c: c}, nil
+}
+
+// CheckNamedValue accepts all arguments unchanged, so they are recorded as
+// passed to database/sql
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.r.exec(query, values(args), c.inTx); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.r.query(query, values(args), c.inTx)
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	if len(args) == 0 {
+		return nil
+	}
+	v := make([]driver.Value, len(args))
+	for i, arg := range args {
+		v[i] = arg.Value
+	}
+	return v
+}
+
+type tx struct {
+	c *conn
+}
+
+func (t *tx) Commit() error {
+	t.c.inTx = false
+	return t.c.r.commit()
+}
+
+func (t *tx) Rollback() error {
+	t.c.inTx = false
+	t.c.r.rollback()
+	return nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.c.r.exec(s.query, args, s.c.inTx); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.r.query(s.query, args, s.c.inTx)
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
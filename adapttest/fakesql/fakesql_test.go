@@ -0,0 +1,168 @@
+package fakesql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func eventStrings(events []Event) []string {
+	s := make([]string, 0, len(events))
+	for _, e := range events {
+		s = append(s, e.String())
+	}
+	return s
+}
+
+func TestRecorder(t *testing.T) {
+	db, rec := Open()
+	defer func() {
+		_ = db.Close()
+	}()
+
+	errInjected := errors.New("injected")
+	rec.FailStatement(3, errInjected)
+
+	if _, err := db.Exec("CREATE TABLE a (id INT)"); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if _, err = tx.Exec("INSERT INTO a VALUES (?)", 1); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if _, err = tx.Exec("INSERT INTO a VALUES (?)", 2); !errors.Is(err, errInjected) {
+		t.Fatalf("Exec() error = %v, want %v", err, errInjected)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	want := []string{
+		"exec: CREATE TABLE a (id INT)",
+		"begin",
+		"exec: INSERT INTO a VALUES (?)",
+		"exec: INSERT INTO a VALUES (?)",
+		"rollback",
+	}
+	events := rec.Events()
+	if got := eventStrings(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Events() = %q, want %q", got, want)
+	}
+	if events[0].InTx || !events[2].InTx {
+		t.Errorf("Events() InTx = %v, %v, want false, true", events[0].InTx, events[2].InTx)
+	}
+	if !reflect.DeepEqual(events[2].Args, []driver.Value{1}) {
+		t.Errorf("Events()[2].Args = %v, want [1]", events[2].Args)
+	}
+	if !errors.Is(events[3].Err, errInjected) {
+		t.Errorf("Events()[3].Err = %v, want %v", events[3].Err, errInjected)
+	}
+
+	rec.Reset()
+	if got := rec.Statements(); len(got) != 0 {
+		t.Errorf("Statements() after Reset() = %q, want none", got)
+	}
+}
+
+func TestRecorder_FailCommit(t *testing.T) {
+	db, rec := Open()
+	defer func() {
+		_ = db.Close()
+	}()
+
+	errInjected := errors.New("injected")
+	rec.FailCommit(errInjected)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err = tx.Commit(); !errors.Is(err, errInjected) {
+		t.Fatalf("Commit() error = %v, want %v", err, errInjected)
+	}
+
+	rec.FailBegin(errInjected)
+	if _, err = db.Begin(); !errors.Is(err, errInjected) {
+		t.Fatalf("Begin() error = %v, want %v", err, errInjected)
+	}
+
+	want := []string{"begin", "commit", "begin"}
+	if got := eventStrings(rec.Events()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Events() = %q, want %q", got, want)
+	}
+}
+
+func TestRecorder_OnTxEnd(t *testing.T) {
+	db, rec := Open()
+	defer func() {
+		_ = db.Close()
+	}()
+
+	var ended []bool
+	rec.OnTxEnd(func(committed bool) {
+		ended = append(ended, committed)
+	})
+
+	end := func(commit bool) {
+		t.Helper()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		if commit {
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}
+	end(true)
+	end(false)
+	rec.FailCommit(errors.New("injected"))
+	end(true)
+
+	if want := []bool{true, false, false}; !reflect.DeepEqual(ended, want) {
+		t.Errorf("OnTxEnd() handler calls = %v, want %v", ended, want)
+	}
+}
+
+func TestRecorder_Handlers(t *testing.T) {
+	db, rec := Open()
+	defer func() {
+		_ = db.Close()
+	}()
+
+	tables := map[string]bool{}
+	rec.OnExec(func(query string, args []driver.Value) error {
+		if query == "DROP TABLE a" && !tables["a"] {
+			return errors.New("no such table")
+		}
+		tables["a"] = query == "CREATE TABLE a"
+		return nil
+	})
+	rec.OnQuery(func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		var values [][]driver.Value
+		if tables["a"] {
+			values = append(values, []driver.Value{"a"})
+		}
+		return []string{"name"}, values, nil
+	})
+
+	if _, err := db.Exec("DROP TABLE a"); err == nil {
+		t.Fatalf("Exec() error = nil, want error")
+	}
+	if _, err := db.Exec("CREATE TABLE a"); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM tables").Scan(&name); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if name != "a" {
+		t.Errorf("QueryRow() = %q, want %q", name, "a")
+	}
+}
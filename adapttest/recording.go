@@ -0,0 +1,247 @@
+package adapttest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/harwoeck/adapt"
+	"github.com/harwoeck/adapt/adapttest/fakesql"
+)
+
+// RecordingOption provides configuration values for a RecordingDriver
+type RecordingOption func(*RecordingDriver)
+
+// RecordingDisableTx lets the RecordingDriver report that it doesn't support
+// transactions, so adapt executes all statements directly on the *sql.DB.
+func RecordingDisableTx() RecordingOption {
+	return func(d *RecordingDriver) {
+		d.txDisabled = true
+	}
+}
+
+/*
+RecordingDriver is an adapt.DatabaseDriver backed by the fake database/sql
+driver of package fakesql. Statements of migrations are executed on the fake
+*sql.DB and therefore recorded, together with all transaction operations. The
+meta-storage (migrations and journal) is kept in memory.
+
+adapt closes its Driver after every operation, but Close doesn't discard any
+state of a RecordingDriver. The same RecordingDriver can therefore be passed
+to several operations, e.g. adapt.Migrate followed by adapt.Rollback.
+
+To test the execution path of adapt.DatabaseDriverCustomMigration embed
+*RecordingDriver in a type that implements Migrate.
+*/
+type RecordingDriver struct {
+	db         *sql.DB
+	rec        *fakesql.Recorder
+	txDisabled bool
+
+	mu         sync.Mutex
+	migrations []*adapt.Migration
+	journal    []*adapt.JournalEntry
+	// removals are migrations deleted within the current transaction, which
+	// are removed from the meta-storage when it is committed
+	removals []string
+}
+
+// NewRecordingDriver returns a new RecordingDriver without any applied
+// migrations
+func NewRecordingDriver(opts ...RecordingOption) *RecordingDriver {
+	db, rec := fakesql.Open()
+	d := &RecordingDriver{
+		db:  db,
+		rec: rec,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	rec.OnTxEnd(d.txEnd)
+	return d
+}
+
+// Recorder returns the fakesql.Recorder of the underlying *sql.DB. It can be
+// used to inspect executed statements and to inject errors.
+func (d *RecordingDriver) Recorder() *fakesql.Recorder {
+	return d.rec
+}
+
+// Migrations returns a copy of all migrations stored in the meta-storage
+func (d *RecordingDriver) Migrations() []adapt.Migration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	migrations := make([]adapt.Migration, 0, len(d.migrations))
+	for _, m := range d.migrations {
+		migrations = append(migrations, *m)
+	}
+	return migrations
+}
+
+func (d *RecordingDriver) Name() string {
+	return "driver_recording"
+}
+
+func (d *RecordingDriver) Init(*slog.Logger) error {
+	return nil
+}
+
+func (d *RecordingDriver) Healthy() error {
+	return d.db.Ping()
+}
+
+func (d *RecordingDriver) SupportsLocks() bool {
+	return false
+}
+
+func (d *RecordingDriver) AcquireLock() error {
+	panic("not supported")
+}
+
+func (d *RecordingDriver) ReleaseLock() error {
+	panic("not supported")
+}
+
+func (d *RecordingDriver) ListMigrations() ([]*adapt.Migration, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	migrations := make([]*adapt.Migration, 0, len(d.migrations))
+	for _, m := range d.migrations {
+		c := *m
+		migrations = append(migrations, &c)
+	}
+	return migrations, nil
+}
+
+func (d *RecordingDriver) AddMigration(m *adapt.Migration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, item := range d.migrations {
+		if item.ID == m.ID {
+			return fmt.Errorf("adapttest: migration %s already exists", m.ID)
+		}
+	}
+	c := *m
+	d.migrations = append(d.migrations, &c)
+	return nil
+}
+
+func (d *RecordingDriver) SetMigrationToFinished(migrationID string) error {
+	return d.update(migrationID, func(m *adapt.Migration) {
+		now := time.Now().UTC()
+		m.Finished = &now
+	})
+}
+
+func (d *RecordingDriver) UpdateMigrationHash(migrationID string, hash *string) error {
+	return d.update(migrationID, func(m *adapt.Migration) {
+		m.Hash = hash
+	})
+}
+
+func (d *RecordingDriver) update(migrationID string, fn func(m *adapt.Migration)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, item := range d.migrations {
+		if item.ID == migrationID {
+			fn(item)
+			return nil
+		}
+	}
+	return fmt.Errorf("adapttest: migration %s missing", migrationID)
+}
+
+func (d *RecordingDriver) RemoveMigration(migrationID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, item := range d.migrations {
+		if item.ID == migrationID {
+			d.migrations = append(d.migrations[:i], d.migrations[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("adapttest: migration %s missing", migrationID)
+}
+
+// DeleteMigration executes a recorded DELETE statement on target, so it shows
+// up within the transaction of a down migration. When target is a transaction
+// the migration is only removed from the meta-storage once it is committed,
+// while a rollback keeps it. As adapt never runs transactions concurrently,
+// the removal is staged until the next transaction of the Recorder ends.
+func (d *RecordingDriver) DeleteMigration(migrationID string, target adapt.DBTarget) error {
+	_, err := target.Exec("DELETE FROM _adapt_migrations WHERE id=?", migrationID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := target.(*sql.Tx); ok {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.removals = append(d.removals, migrationID)
+		return nil
+	}
+	return d.RemoveMigration(migrationID)
+}
+
+// txEnd applies the removals staged by DeleteMigration when the transaction
+// was committed and discards them otherwise
+func (d *RecordingDriver) txEnd(committed bool) {
+	d.mu.Lock()
+	removals := d.removals
+	d.removals = nil
+	d.mu.Unlock()
+
+	if !committed {
+		return
+	}
+	for _, id := range removals {
+		_ = d.RemoveMigration(id)
+	}
+}
+
+func (d *RecordingDriver) AddJournalEntry(entry *adapt.JournalEntry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c := *entry
+	d.journal = append(d.journal, &c)
+	return nil
+}
+
+func (d *RecordingDriver) ListJournalEntries() ([]*adapt.JournalEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := make([]*adapt.JournalEntry, 0, len(d.journal))
+	for _, entry := range d.journal {
+		c := *entry
+		entries = append(entries, &c)
+	}
+	return entries, nil
+}
+
+// Close is a no-op, so the RecordingDriver can be reused by later operations
+func (d *RecordingDriver) Close() error {
+	return nil
+}
+
+func (d *RecordingDriver) DB() *sql.DB {
+	return d.db
+}
+
+func (d *RecordingDriver) SupportsTx() bool {
+	return !d.txDisabled
+}
+
+func (d *RecordingDriver) TxBeginOpts() (ctx context.Context, opts *sql.TxOptions) {
+	return context.Background(), nil
+}
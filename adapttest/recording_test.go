@@ -0,0 +1,63 @@
+package adapttest
+
+import (
+	"testing"
+
+	"github.com/harwoeck/adapt"
+)
+
+func TestRecordingDriver_DeleteMigration(t *testing.T) {
+	d := NewRecordingDriver()
+	for _, id := range []string{"1_a", "2_b"} {
+		if err := d.AddMigration(&adapt.Migration{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func() []string {
+		var ids []string
+		for _, m := range d.Migrations() {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+	deleteInTx := func(id string, commit bool) {
+		t.Helper()
+		tx, err := d.DB().Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = d.DeleteMigration(id, tx); err != nil {
+			t.Fatalf("DeleteMigration() error = %v", err)
+		}
+		if got := len(d.Migrations()); got != 2 {
+			t.Errorf("DeleteMigration() removed migration before the transaction ended, %d left", got)
+		}
+		if commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleteInTx("2_b", false)
+	if got := ids(); len(got) != 2 {
+		t.Errorf("Migrations() after rollback = %v, want both kept", got)
+	}
+
+	deleteInTx("2_b", true)
+	if got := ids(); len(got) != 1 || got[0] != "1_a" {
+		t.Errorf("Migrations() after commit = %v, want [1_a]", got)
+	}
+
+	// without a transaction the migration is removed immediately
+	if err := d.DeleteMigration("1_a", d.DB()); err != nil {
+		t.Fatalf("DeleteMigration() error = %v", err)
+	}
+	if got := ids(); len(got) != 0 {
+		t.Errorf("Migrations() = %v, want none", got)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Package adapttest provides helpers for testing migrations with the standard
+testing package.
+
+VerifyRoundTrip checks that every down migration reverts its up migration:
+
+	func TestMigrations(t *testing.T) {
+		adapttest.VerifyRoundTrip(t, func() adapt.DatabaseDriver {
+			db, err := sql.Open("postgres", os.Getenv("TEST_DATABASE_URL"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return adapt.NewPostgresDriver(db)
+		}, adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		})
+	}
+
+NewRecordingDriver returns a DatabaseDriver that doesn't need a database. It
+records every executed statement and transaction operation and can inject
+errors (see package fakesql), so tests can assert what adapt executes.
+*/
+package adapttest
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/harwoeck/adapt"
+)
+
+// DriverFactory creates a new DatabaseDriver connected to the database under
+// test. It is called for every single step of VerifyRoundTrip, because adapt
+// closes the Driver after each operation. Therefore, every call must return a
+// new Driver (and most often a new *sql.DB) connected to the same database.
+type DriverFactory func() adapt.DatabaseDriver
+
+// RoundTripOption provides configuration values for VerifyRoundTrip
+type RoundTripOption func(*roundTrip)
+
+type roundTrip struct {
+	snapshot SnapshotFunc
+	options  []adapt.Option
+}
+
+// RoundTripSnapshot sets the SnapshotFunc used to capture the schema between
+// steps. By default, the adapt.SchemaInspector implementation of the Driver is
+// used, which is provided by the PostgreSQL, MySQL and SQLite drivers. Custom
+// queries can be used with QuerySnapshot.
+func RoundTripSnapshot(snapshot SnapshotFunc) RoundTripOption {
+	return func(r *roundTrip) {
+		r.snapshot = snapshot
+	}
+}
+
+// RoundTripWithOptions passes options to all adapt operations performed by
+// VerifyRoundTrip, e.g. adapt.OrderBy. By default, all logging output is
+// discarded.
+func RoundTripWithOptions(options ...adapt.Option) RoundTripOption {
+	return func(r *roundTrip) {
+		r.options = append(r.options, options...)
+	}
+}
+
+/*
+VerifyRoundTrip applies all migrations of sources one by one to the empty
+database provided by driverFactory. After applying a migration it reverts it
+using its stored down migration (see adapt.Rollback) and applies it again. A
+schema snapshot is taken before and after every step: the snapshot after the
+rollback must equal the one before the migration, and the snapshot after
+reapplying must equal the one after the first application. Differences are
+reported as test errors containing a diff of the snapshots.
+
+Migrations without a down migration are reported as test errors and stay
+applied. Repeatable migrations are applied without a round trip, as adapt never
+reverts them.
+*/
+func VerifyRoundTrip(t testing.TB, driverFactory DriverFactory, sources adapt.SourceCollection, opts ...RoundTripOption) {
+	t.Helper()
+
+	r := &roundTrip{
+		options: []adapt.Option{adapt.CustomLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	// the plan contains all migrations in the order they are applied. It also
+	// creates adapt's meta-storage, so that it is part of every snapshot.
+	plan, err := adapt.Plan(driverFactory(), sources, r.options...)
+	if err != nil {
+		t.Fatalf("adapttest: planning migrations failed: %v", err)
+	}
+	if len(plan.Rollback) > 0 {
+		t.Fatalf("adapttest: database isn't empty, as it contains unknown migrations")
+	}
+
+	ids := make([]string, 0, len(plan.Apply))
+	for idx, am := range plan.Apply {
+		ids = append(ids, am.ID)
+		limited := limitSources(sources, ids)
+
+		before := r.takeSnapshot(t, driverFactory, "before applying "+am.ID)
+		r.migrate(t, driverFactory, limited, am.ID)
+		after := r.takeSnapshot(t, driverFactory, "after applying "+am.ID)
+
+		if am.Repeatable {
+			continue
+		}
+		if !hasDown(am) {
+			t.Errorf("adapttest: migration %s (%d/%d) has no down migration", am.ID, idx+1, len(plan.Apply))
+			continue
+		}
+
+		_, err = adapt.Rollback(driverFactory(), limited, 1, r.options...)
+		if err != nil {
+			t.Fatalf("adapttest: rolling back %s failed: %v", am.ID, err)
+		}
+		reverted := r.takeSnapshot(t, driverFactory, "after rolling back "+am.ID)
+		if diff := Diff(before, reverted); len(diff) > 0 {
+			t.Errorf("adapttest: down migration of %s doesn't restore the previous schema:\n%s", am.ID, diff)
+		}
+
+		r.migrate(t, driverFactory, limited, am.ID)
+		reapplied := r.takeSnapshot(t, driverFactory, "after reapplying "+am.ID)
+		if diff := Diff(after, reapplied); len(diff) > 0 {
+			t.Errorf("adapttest: reapplying %s after its down migration results in a different schema:\n%s", am.ID, diff)
+		}
+	}
+}
+
+func (r *roundTrip) migrate(t testing.TB, driverFactory DriverFactory, sources adapt.SourceCollection, id string) {
+	t.Helper()
+
+	err := adapt.Migrate("adapttest", driverFactory(), sources, r.options...)
+	if err != nil {
+		t.Fatalf("adapttest: applying %s failed: %v", id, err)
+	}
+}
+
+func (r *roundTrip) takeSnapshot(t testing.TB, driverFactory DriverFactory, step string) string {
+	t.Helper()
+
+	driver := driverFactory()
+	err := driver.Init(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("adapttest: initializing driver for snapshot %s failed: %v", step, err)
+	}
+	defer func() {
+		_ = driver.Close()
+	}()
+
+	var s string
+	if r.snapshot != nil {
+		s, err = r.snapshot(driver.DB())
+	} else {
+		s, err = inspectorSnapshot(driver)
+	}
+	if err != nil {
+		t.Fatalf("adapttest: taking snapshot %s failed: %v", step, err)
+	}
+	return s
+}
+
+func hasDown(am *adapt.AvailableMigration) bool {
+	switch src := am.Source.(type) {
+	case adapt.SqlStatementsSource:
+		down, err := src.GetParsedDownMigration(am.ID)
+		return err == nil && down != nil
+	case adapt.HookSource:
+		hook := src.GetHook(am.ID)
+		return hook.MigrateDown != nil && hook.MigrateDown() != nil
+	default:
+		return false
+	}
+}
+
+// Diff compares two snapshots line by line. It returns an empty string when
+// they are equal, otherwise every line only contained in a is prefixed with
+// "- " and every line only contained in b with "+ ".
+func Diff(a string, b string) string {
+	aLines, bLines := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	count := func(lines []string) map[string]int {
+		m := make(map[string]int, len(lines))
+		for _, line := range lines {
+			m[line]++
+		}
+		return m
+	}
+	inA, inB := count(aLines), count(bLines)
+
+	var diff strings.Builder
+	for _, line := range aLines {
+		if inB[line] > 0 {
+			inB[line]--
+			continue
+		}
+		diff.WriteString("- " + line + "\n")
+	}
+	for _, line := range bLines {
+		if inA[line] > 0 {
+			inA[line]--
+			continue
+		}
+		diff.WriteString("+ " + line + "\n")
+	}
+	return diff.String()
+}
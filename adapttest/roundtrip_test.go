@@ -0,0 +1,193 @@
+package adapttest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/harwoeck/adapt"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"reordered", "a\nb\n", "b\na\n", ""},
+		{"removed", "a\nb\n", "a\n", "- b\n"},
+		{"added", "a\n", "a\nc\n", "+ c\n"},
+		{"duplicate", "a\na\n", "a\n", "- a\n"},
+		{"changed", "t\tid\tinteger\n", "t\tid\tbigint\n", "- t\tid\tinteger\n+ t\tid\tbigint\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.a, tt.b); got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_limitSources(t *testing.T) {
+	sources := limitSources(adapt.SourceCollection{
+		adapt.NewMemoryFSSource(map[string]string{
+			"1_a.up.sql": "CREATE TABLE a (id INT);",
+			"2_b.up.sql": "CREATE TABLE b (id INT);",
+		}),
+		adapt.NewCodePackageSource(map[string]adapt.Hook{
+			"3_c": {MigrateUp: func() error { return nil }},
+		}),
+	}, []string{"1_a", "3_c"})
+
+	var got []string
+	for _, src := range sources {
+		if err := src.Init(slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+			t.Fatal(err)
+		}
+		ids, err := src.ListMigrations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, ids...)
+	}
+	if want := []string{"1_a", "3_c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListMigrations() = %v, want %v", got, want)
+	}
+
+	if _, ok := sources[0].(adapt.SqlStatementsSource); !ok {
+		t.Errorf("limited source %T isn't a SqlStatementsSource", sources[0])
+	}
+	if _, ok := sources[1].(adapt.HookSource); !ok {
+		t.Errorf("limited source %T isn't a HookSource", sources[1])
+	}
+}
+
+// errorRecorder captures errors reported by VerifyRoundTrip instead of failing
+// the test
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// tableModel simulates the tables of the fake database by interpreting
+// CREATE TABLE and DROP TABLE statements
+func tableModel(d *RecordingDriver) SnapshotFunc {
+	tables := make(map[string]bool)
+	d.Recorder().OnExec(func(query string, args []driver.Value) error {
+		fields := strings.Fields(strings.TrimSuffix(query, ";"))
+		if len(fields) >= 3 && fields[1] == "TABLE" {
+			switch fields[0] {
+			case "CREATE":
+				tables[fields[2]] = true
+			case "DROP":
+				delete(tables, fields[2])
+			}
+		}
+		return nil
+	})
+
+	return func(*sql.DB) (string, error) {
+		var names []string
+		for name := range tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, "\n"), nil
+	}
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		wantErrors int
+	}{
+		{
+			name: "reverting down migrations",
+			files: map[string]string{
+				"1_a.sql": "-- +adapt Up\nCREATE TABLE a (id INT);\n-- +adapt Down\nDROP TABLE a;",
+				"2_b.sql": "-- +adapt Up\nCREATE TABLE b (id INT);\n-- +adapt Down\nDROP TABLE b;",
+			},
+		},
+		{
+			name: "down migration reverting too much",
+			files: map[string]string{
+				"1_a.sql": "-- +adapt Up\nCREATE TABLE a (id INT);\n-- +adapt Down\nDROP TABLE a;",
+				"2_b.sql": "-- +adapt Up\nCREATE TABLE b (id INT);\n-- +adapt Down\nDROP TABLE b;\nDROP TABLE a;",
+			},
+			// the diff after rolling back and after reapplying
+			wantErrors: 2,
+		},
+		{
+			name: "missing down migration",
+			files: map[string]string{
+				"1_a.up.sql": "CREATE TABLE a (id INT);",
+			},
+			wantErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewRecordingDriver()
+			r := &errorRecorder{TB: t}
+
+			VerifyRoundTrip(r, func() adapt.DatabaseDriver {
+				return d
+			}, adapt.SourceCollection{
+				adapt.NewMemoryFSSource(tt.files),
+			}, RoundTripSnapshot(tableModel(d)))
+
+			if len(r.errors) != tt.wantErrors {
+				t.Errorf("VerifyRoundTrip() reported %d errors, want %d: %q", len(r.errors), tt.wantErrors, r.errors)
+			}
+		})
+	}
+}
+
+// TestVerifyRoundTrip_FilesystemSource reuses a filesystem based source for
+// every Plan, Migrate and Rollback call of the round trip, which initializes it
+// multiple times
+func TestVerifyRoundTrip_FilesystemSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":    "# migrations",
+		"1_a.sql":      "-- +adapt Up\nCREATE TABLE a (id INT);\n-- +adapt Down\nDROP TABLE a;",
+		"2_b.up.sql":   "CREATE TABLE b (id INT);",
+		"2_b.down.sql": "DROP TABLE b;",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewRecordingDriver()
+	r := &errorRecorder{TB: t}
+	VerifyRoundTrip(r, func() adapt.DatabaseDriver {
+		return d
+	}, adapt.SourceCollection{
+		adapt.NewFilesystemSource(dir, adapt.FilesystemIgnore("*.md")),
+	}, RoundTripSnapshot(tableModel(d)))
+
+	if len(r.errors) > 0 {
+		t.Errorf("VerifyRoundTrip() reported errors: %q", r.errors)
+	}
+	if got := d.Migrations(); len(got) != 2 {
+		t.Errorf("VerifyRoundTrip() left %d stored migrations, want 2", len(got))
+	}
+}
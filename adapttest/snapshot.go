@@ -0,0 +1,117 @@
+package adapttest
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/harwoeck/adapt"
+)
+
+// SnapshotFunc captures the schema of a database as text. Equal schemas must
+// produce equal snapshots. Snapshots are compared line by line using Diff.
+type SnapshotFunc func(db *sql.DB) (string, error)
+
+// inspectorSnapshot captures the schema using the adapt.SchemaInspector
+// implementation of driver
+func inspectorSnapshot(driver adapt.DatabaseDriver) (string, error) {
+	inspector, ok := driver.(adapt.SchemaInspector)
+	if !ok {
+		return "", fmt.Errorf("driver %q doesn't implement adapt.SchemaInspector, use RoundTripSnapshot", driver.Name())
+	}
+
+	snapshot, err := inspector.InspectSchema()
+	if err != nil {
+		return "", err
+	}
+	return snapshot.String(), nil
+}
+
+// QuerySnapshot returns a SnapshotFunc that executes all queries and renders
+// every resulting row as a single line of tab separated values. Queries should
+// order their rows to produce stable snapshots.
+func QuerySnapshot(queries ...string) SnapshotFunc {
+	return func(db *sql.DB) (string, error) {
+		var b strings.Builder
+		for _, query := range queries {
+			if err := writeRows(&b, db, query); err != nil {
+				return "", err
+			}
+		}
+		return b.String(), nil
+	}
+}
+
+func writeRows(b *strings.Builder, db *sql.DB, query string) error {
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			if v.Valid {
+				b.WriteString(v.String)
+			} else {
+				b.WriteString("NULL")
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return rows.Err()
+}
+
+var (
+	// PostgresSnapshot captures columns, constraints and indexes of all
+	// non-system schemas of a PostgreSQL database. NOT NULL constraints are
+	// named after OIDs and therefore excluded, they are covered by is_nullable.
+	PostgresSnapshot = QuerySnapshot(
+		`SELECT table_schema, table_name, column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_schema NOT IN ('pg_catalog', 'information_schema') ORDER BY table_schema, table_name, ordinal_position`,
+		`SELECT table_schema, table_name, constraint_name, constraint_type FROM information_schema.table_constraints WHERE table_schema NOT IN ('pg_catalog', 'information_schema') AND constraint_name !~ '^[0-9]+_[0-9]+_[0-9]+_not_null$' ORDER BY table_schema, table_name, constraint_name`,
+		`SELECT schemaname, tablename, indexname, indexdef FROM pg_indexes WHERE schemaname NOT IN ('pg_catalog', 'information_schema') ORDER BY schemaname, tablename, indexname`,
+	)
+	// MySQLSnapshot captures columns and indexes of the current MySQL database
+	MySQLSnapshot = QuerySnapshot(
+		`SELECT table_name, column_name, column_type, is_nullable, column_default, extra FROM information_schema.columns WHERE table_schema = DATABASE() ORDER BY table_name, ordinal_position`,
+		`SELECT table_name, index_name, non_unique, seq_in_index, column_name FROM information_schema.statistics WHERE table_schema = DATABASE() ORDER BY table_name, index_name, seq_in_index`,
+	)
+	// SQLiteSnapshot captures all schema objects of a SQLite database
+	SQLiteSnapshot = QuerySnapshot(
+		`SELECT type, name, tbl_name, sql FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' ORDER BY type, name`,
+	)
+)
+
+// DefaultSnapshot selects PostgresSnapshot, MySQLSnapshot or SQLiteSnapshot by
+// the Name of the built-in adapt drivers.
+func DefaultSnapshot(driver adapt.Driver) (SnapshotFunc, error) {
+	switch driver.Name() {
+	case "driver_postgres":
+		return PostgresSnapshot, nil
+	case "driver_mysql":
+		return MySQLSnapshot, nil
+	case "driver_sqlite":
+		return SQLiteSnapshot, nil
+	default:
+		return nil, fmt.Errorf("no default snapshot for driver %q available, use RoundTripSnapshot", driver.Name())
+	}
+}
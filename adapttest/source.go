@@ -0,0 +1,68 @@
+package adapttest
+
+import (
+	"slices"
+
+	"github.com/harwoeck/adapt"
+)
+
+// limitSources wraps every Source, so that only migrations contained in ids
+// are listed. The wrappers keep implementing adapt.SqlStatementsSource or
+// adapt.HookSource like the wrapped Source.
+func limitSources(sources adapt.SourceCollection, ids []string) adapt.SourceCollection {
+	limited := make(adapt.SourceCollection, 0, len(sources))
+	for _, src := range sources {
+		base := limitedSource{Source: src, ids: ids}
+		switch s := src.(type) {
+		case adapt.SqlStatementsSource:
+			limited = append(limited, &limitedSqlStatementsSource{limitedSource: base, src: s})
+		case adapt.HookSource:
+			limited = append(limited, &limitedHookSource{limitedSource: base, src: s})
+		default:
+			limited = append(limited, &base)
+		}
+	}
+	return limited
+}
+
+type limitedSource struct {
+	adapt.Source
+	ids []string
+}
+
+func (s *limitedSource) ListMigrations() ([]string, error) {
+	all, err := s.Source.ListMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var listed []string
+	for _, id := range all {
+		if slices.Contains(s.ids, id) {
+			listed = append(listed, id)
+		}
+	}
+	return listed, nil
+}
+
+type limitedSqlStatementsSource struct {
+	limitedSource
+	src adapt.SqlStatementsSource
+}
+
+func (s *limitedSqlStatementsSource) GetParsedUpMigration(id string) (*adapt.ParsedMigration, error) {
+	return s.src.GetParsedUpMigration(id)
+}
+
+func (s *limitedSqlStatementsSource) GetParsedDownMigration(id string) (*adapt.ParsedMigration, error) {
+	return s.src.GetParsedDownMigration(id)
+}
+
+type limitedHookSource struct {
+	limitedSource
+	src adapt.HookSource
+}
+
+func (s *limitedHookSource) GetHook(id string) adapt.Hook {
+	return s.src.GetHook(id)
+}
@@ -0,0 +1,47 @@
+package adapt
+
+import "strings"
+
+const (
+	// BaselineExecutor is the Migration.Executor of all migrations recorded by
+	// Baseline or BaselineOnEmptyMeta
+	BaselineExecutor = "adapt/baseline"
+	// BaselineDeploymentPrefix is the prefix of the Migration.Deployment of all
+	// migrations recorded by Baseline or BaselineOnEmptyMeta
+	BaselineDeploymentPrefix = "BASELINE-"
+)
+
+// IsBaseline reports whether the Migration was recorded by Baseline or
+// BaselineOnEmptyMeta without being executed.
+func (m *Migration) IsBaseline() bool {
+	return m.Executor == BaselineExecutor && strings.HasPrefix(m.Deployment, BaselineDeploymentPrefix)
+}
+
+/*
+Baseline records all available migrations up to (and including) upToID as
+applied and finished without executing them. It is used when adopting adapt
+on an existing database, whose schema already contains the changes of these
+migrations. Already applied migrations are left untouched. The recorded
+migrations use BaselineExecutor and a deployment prefixed with
+BaselineDeploymentPrefix, so they can be identified later (see
+Migration.IsBaseline).
+
+Example:
+
+	err := adapt.Baseline(
+		adapt.NewPostgresDriver(db),
+		adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		},
+		"20240101_1200_legacy-schema",
+	)
+*/
+func Baseline(driver Driver, sources SourceCollection, upToID string, options ...Option) error {
+	e, err := newExec(BaselineExecutor, driver, sources, options...)
+	if err != nil {
+		return err
+	}
+	return e.runWith(func() error {
+		return e.stageBaseline(upToID)
+	})
+}
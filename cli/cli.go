@@ -0,0 +1,112 @@
+/*
+Package cli implements the adapt command-line tool. It operates migrations
+outside the application using the same pipeline as adapt.Migrate.
+
+The cmd/adapt binary only supports the "file" driver out of the box, because
+adapt has no external dependencies and therefore doesn't register any
+database/sql drivers. The postgres, mysql and sqlite drivers are only offered
+once a database/sql driver is registered. To operate SQL databases build your
+own binary, which imports the needed database/sql drivers and calls Main:
+
+	package main
+
+	import (
+		"os"
+
+		_ "github.com/lib/pq"
+
+		"github.com/harwoeck/adapt/cli"
+	)
+
+	func main() {
+		os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
+	}
+*/
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// command is a single subcommand of the adapt command-line tool
+type command struct {
+	usage string
+	run   func(env *env, args []string) error
+}
+
+var commands = map[string]command{
+	"status":   {"show the state of all migrations", runStatus},
+	"plan":     {"show the changes \"up\" would perform", runPlan},
+	"up":       {"apply all pending migrations", runUp},
+	"down":     {"revert the last applied migrations", runDown},
+	"baseline": {"record migrations as applied without executing them", runBaseline},
+	"repair":   {"repair hash mismatches and unfinished migrations", runRepair},
+	"validate": {"check the migration sources without a database", runValidate},
+	"drift":    {"compare the live schema against the recorded snapshot", runDrift},
+	"new":      {"create new migration files", runNew},
+}
+
+// errUsage signals that the arguments were invalid and usage was already
+// printed.
+var errUsage = errors.New("usage error")
+
+// env bundles the output writers of a single invocation
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Main runs the adapt command-line tool with args (without the program name)
+// and returns the process exit code: 0 on success, 1 when the command failed
+// and 2 for invalid arguments.
+func Main(args []string, stdout io.Writer, stderr io.Writer) int {
+	e := &env{stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		e.usage()
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "adapt: unknown command %q\n\n", args[0])
+		e.usage()
+		return 2
+	}
+
+	err := cmd.run(e, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		_, _ = fmt.Fprintf(stderr, "adapt %s: %v\n", args[0], err)
+		return 1
+	}
+}
+
+func (e *env) usage() {
+	_, _ = fmt.Fprintln(e.stderr, "usage: adapt <command> [flags]")
+	_, _ = fmt.Fprintln(e.stderr)
+	_, _ = fmt.Fprintln(e.stderr, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(e.stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+
+	_, _ = fmt.Fprintln(e.stderr)
+	_, _ = fmt.Fprintln(e.stderr, "Run \"adapt <command> -h\" for the flags of a command.")
+}
@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harwoeck/adapt/adapttest/fakesql"
+)
+
+func TestMain_Commands(t *testing.T) {
+	dir := t.TempDir()
+	sqlDir := filepath.Join(dir, "sql")
+	if err := os.Mkdir(sqlDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"20240101_1200_init.up.sql":    "CREATE TABLE a (id INT);",
+		"20240101_1200_init.down.sql":  "DROP TABLE a;",
+		"20240102_1200_users.up.sql":   "CREATE TABLE users (id INT);",
+		"20240102_1200_users.down.sql": "DROP TABLE users;",
+	} {
+		if err := os.WriteFile(filepath.Join(sqlDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	driverArgs := []string{"-driver", "file", "-dsn", filepath.Join(dir, "meta.json"), "-dir", sqlDir}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{"no command", nil, 2, ""},
+		{"unknown command", []string{"unknown"}, 2, ""},
+		{"invalid format", append([]string{"status", "-format", "yaml"}, driverArgs...), 2, ""},
+		{"missing dsn", []string{"status", "-driver", "file", "-dir", sqlDir}, 1, ""},
+		{"unsupported driver", []string{"status", "-driver", "oracle", "-dsn", "x", "-dir", sqlDir}, 1, ""},
+		{"validate", []string{"validate", "-dir", sqlDir}, 0, "all migrations are valid\n"},
+		{"validate with ordering", []string{"validate", "-dir", sqlDir, "-order", "timestamp"}, 0, "all migrations are valid\n"},
+		{"plan", append([]string{"plan"}, driverArgs...), 0, "apply  20240101_1200_init\napply  20240102_1200_users\n"},
+		{"baseline without id", append([]string{"baseline"}, driverArgs...), 2, ""},
+		{"baseline", append([]string{"baseline", "-to", "20240101_1200_init"}, driverArgs...), 0, "recorded baseline up to 20240101_1200_init\n"},
+		{"plan after baseline", append([]string{"plan", "-format", "json"}, driverArgs...), 0, "{\n  \"rollback\": [],\n  \"apply\": [\n    \"20240102_1200_users\"\n  ]\n}\n"},
+		{"down requires database driver", append([]string{"down"}, driverArgs...), 1, ""},
+		{"drift requires schema inspector", append([]string{"drift"}, driverArgs...), 1, ""},
+		{"repair without reason", append([]string{"repair", "-by", "jane.doe"}, driverArgs...), 1, ""},
+		{"repair", append([]string{"repair", "-by", "jane.doe", "-reason", "check", "-hashes"}, driverArgs...), 0, "nothing to repair\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := Main(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("Main() = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			if tt.wantCode == 0 && stdout.String() != tt.wantStdout {
+				t.Errorf("Main() stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Main(append([]string{"status", "-format", "json"}, driverArgs...), &stdout, &stderr); code != 0 {
+		t.Fatalf("status = %d (stderr: %s)", code, stderr.String())
+	}
+	var statuses []migrationJSON
+	if err := json.Unmarshal(stdout.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range statuses {
+		got = append(got, s.ID+"="+s.State)
+	}
+	if want := []string{"20240101_1200_init=applied", "20240102_1200_users=pending"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("status = %v, want %v", got, want)
+	}
+}
+
+func TestMain_Validate(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"20240101_1200_idx.up.sql":   "CREATE INDEX CONCURRENTLY idx ON a (id);",
+		"20240101_1200_idx.down.sql": "DROP INDEX idx;",
+		"20240102_1200_b.up.sql":     "CREATE TABLE b (id INT);",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Main([]string{"validate", "-dir", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("validate = %d (stderr: %s)", code, stderr.String())
+	}
+	if want := "SEVERITY  ID               CODE          MESSAGE\nwarning   20240102_1200_b  missing_down  migration has no down migration\n"; stdout.String() != want {
+		t.Errorf("validate stdout = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := Main([]string{"validate", "-dir", dir, "-dialect", "postgres", "-format", "json"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("validate -dialect postgres = %d, want 1", code)
+	}
+	var result struct {
+		Valid       bool
+		Diagnostics []struct{ Code string }
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || len(result.Diagnostics) != 2 || result.Diagnostics[0].Code != "dialect_hazard" {
+		t.Errorf("validate -dialect postgres = %+v", result)
+	}
+
+	if code := Main([]string{"validate", "-dir", dir, "-dialect", "oracle"}, &stdout, &stderr); code != 2 {
+		t.Errorf("validate -dialect oracle = %d, want 2", code)
+	}
+}
+
+func TestMain_New(t *testing.T) {
+	dir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	if code := Main([]string{"new", "-dir", dir, "Encrypt", "User", "E-Mail"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("new = %d (stderr: %s)", code, stderr.String())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("new created %d files, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		if !strings.Contains(entry.Name(), "_encrypt-user-e-mail.") {
+			t.Errorf("new created %q", entry.Name())
+		}
+	}
+
+	if code := Main([]string{"new", "-dir", dir}, &stdout, &stderr); code != 2 {
+		t.Errorf("new without description = %d, want 2", code)
+	}
+	if code := Main([]string{"new", "-dir", dir, "-scheme", "unknown", "x"}, &stdout, &stderr); code != 2 {
+		t.Errorf("new with invalid -scheme = %d, want 2", code)
+	}
+
+	dir = t.TempDir()
+	stdout.Reset()
+	if code := Main([]string{"new", "-dir", dir, "-order", "flyway", "add users"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("new -order flyway = %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "V1__add_users.up.sql") {
+		t.Errorf("new -order flyway output = %q", stdout.String())
+	}
+
+	stdout.Reset()
+	args := []string{"new", "-dir", dir, "-scheme", "numeric", "-width", "3", "-hook-package", "migrations", "backfill"}
+	if code := Main(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("new -hook-package = %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "001_backfill.hook.go") {
+		t.Errorf("new -hook-package output = %q", stdout.String())
+	}
+}
+
+func TestMain_UpMetricsFile(t *testing.T) {
+	dir := t.TempDir()
+	sqlDir := filepath.Join(dir, "sql")
+	if err := os.Mkdir(sqlDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sqlDir, "20240101_1200_init.up.sql"), []byte("CREATE TABLE a (id INT);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	metricsFile := filepath.Join(dir, "adapt.prom")
+
+	// the file driver cannot apply SQL migrations, but the failure is still
+	// written to the metrics file
+	var stdout, stderr bytes.Buffer
+	args := []string{"up", "-driver", "file", "-dsn", filepath.Join(dir, "meta.json"), "-dir", sqlDir, "-metrics-file", metricsFile}
+	if code := Main(args, &stdout, &stderr); code != 1 {
+		t.Fatalf("Main() = %d, want 1 (stderr: %s)", code, stderr.String())
+	}
+
+	buf, err := os.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`adapt_migration_duration_seconds_count{driver="driver_file",executor="adapt-cli",outcome="failure"} 1`,
+		`adapt_failures_total{driver="driver_file",executor="adapt-cli",stage="migrate"} 1`,
+		`adapt_pending_migrations{driver="driver_file"} 1`,
+	} {
+		if !strings.Contains(string(buf), want+"\n") {
+			t.Errorf("metrics file doesn't contain %q:\n%s", want, buf)
+		}
+	}
+}
+
+func TestMain_UpReportsDeployed(t *testing.T) {
+	db, rec := fakesql.Open()
+	sql.Register("adapt-cli-fakesql", db.Driver())
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"20240101_1200_init.up.sql":  "CREATE TABLE a (id INT);",
+		"20240102_1200_users.up.sql": "CREATE TABLE users (id INT);",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args := []string{"up", "-driver", "sqlite", "-sql-driver", "adapt-cli-fakesql", "-dsn", "test", "-dir", dir, "-format", "json"}
+
+	var stdout, stderr bytes.Buffer
+	if code := Main(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Main() = %d (stderr: %s)", code, stderr.String())
+	}
+	var got struct {
+		Rollback []string `json:"rollback"`
+		Apply    []string `json:"apply"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("up output isn't valid JSON: %v", err)
+	}
+	if want := []string{"20240101_1200_init", "20240102_1200_users"}; !reflect.DeepEqual(got.Apply, want) || len(got.Rollback) != 0 {
+		t.Errorf("up output = %s, want applied %v", stdout.String(), want)
+	}
+
+	// a failing migration isn't reported as applied
+	rec.FailStatement(2, errors.New("injected"))
+	rec.Reset()
+	stdout.Reset()
+	if code := Main(args, &stdout, &stderr); code != 1 {
+		t.Fatalf("Main() = %d, want 1", code)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("failed up output = %s, want none", stdout.String())
+	}
+}
+
+func TestMain_WithoutSQLDrivers(t *testing.T) {
+	registered := registeredSQLDrivers
+	registeredSQLDrivers = func() []string { return nil }
+	defer func() { registeredSQLDrivers = registered }()
+
+	var stdout, stderr bytes.Buffer
+	if code := Main([]string{"status", "-h"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("Main() = %d, want 2", code)
+	}
+	if help := stderr.String(); strings.Contains(help, "-sql-driver") || strings.Contains(help, "file, postgres") {
+		t.Errorf("Main() usage offers SQL drivers without registered database/sql drivers:\n%s", help)
+	}
+
+	stderr.Reset()
+	args := []string{"status", "-driver", "postgres", "-dsn", "test", "-dir", t.TempDir()}
+	if code := Main(args, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), `unsupported -driver "postgres"`) {
+		t.Errorf("Main() = %d (stderr: %s), want unsupported -driver", code, stderr.String())
+	}
+}
@@ -0,0 +1,424 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/harwoeck/adapt"
+)
+
+func runStatus(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("status", cfg, true)
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+
+	driver, err := cfg.openDriver()
+	if err != nil {
+		return err
+	}
+	statuses, err := adapt.Status(driver, cfg.sources(), e.options(cfg)...)
+	if err != nil {
+		return err
+	}
+
+	result := make([]migrationJSON, 0, len(statuses))
+	for _, s := range statuses {
+		var m migrationJSON
+		if s.Applied != nil {
+			m = appliedJSON(s.Applied)
+		} else {
+			m = availableJSON(s.Available)
+		}
+		m.State = string(s.State)
+		result = append(result, m)
+	}
+
+	return e.output(cfg, result, func(w io.Writer) {
+		_, _ = fmt.Fprintln(w, "ID\tSTATE\tFINISHED\tEXECUTOR")
+		for _, s := range statuses {
+			finished, executor := "-", "-"
+			if s.Applied != nil {
+				finished, executor = formatTime(s.Applied.Finished), s.Applied.Executor
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, s.State, finished, executor)
+		}
+	})
+}
+
+// planJSON is the JSON representation of an adapt.MigrationPlan
+type planJSON struct {
+	Rollback []string `json:"rollback"`
+	Apply    []string `json:"apply"`
+}
+
+func newPlanJSON(plan *adapt.MigrationPlan) planJSON {
+	p := planJSON{Rollback: []string{}, Apply: []string{}}
+	for _, m := range plan.Rollback {
+		p.Rollback = append(p.Rollback, m.ID)
+	}
+	for _, m := range plan.Apply {
+		p.Apply = append(p.Apply, m.ID)
+	}
+	return p
+}
+
+func (p planJSON) print(w io.Writer, rollbackAction string, applyAction string) {
+	if len(p.Rollback) == 0 && len(p.Apply) == 0 {
+		_, _ = fmt.Fprintln(w, "everything up-to-date")
+		return
+	}
+	printIDs(w, rollbackAction, p.Rollback)
+	printIDs(w, applyAction, p.Apply)
+}
+
+func runPlan(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("plan", cfg, true)
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+
+	driver, err := cfg.openDriver()
+	if err != nil {
+		return err
+	}
+	plan, err := adapt.Plan(driver, cfg.sources(), e.options(cfg)...)
+	if err != nil {
+		return err
+	}
+
+	p := newPlanJSON(plan)
+	return e.output(cfg, p, func(w io.Writer) {
+		p.print(w, "rollback", "apply")
+	})
+}
+
+// deployObserver collects the migrations applied and reverted by Migrate
+type deployObserver struct {
+	adapt.NopObserver
+	deployed planJSON
+}
+
+func (o *deployObserver) OnMigrationEnd(event adapt.MigrationEvent) {
+	if event.Err == nil {
+		o.deployed.Apply = append(o.deployed.Apply, event.MigrationID)
+	}
+}
+
+func (o *deployObserver) OnRollback(event adapt.RollbackEvent) {
+	if event.Err == nil {
+		o.deployed.Rollback = append(o.deployed.Rollback, event.MigrationID)
+	}
+}
+
+func runUp(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("up", cfg, true)
+	recordSnapshot := fs.Bool("record-snapshot", false, "record the schema snapshot checked by \"adapt drift\"")
+	metricsFile := fs.String("metrics-file", "", "write Prometheus metrics of the migration to this file (e.g. for the node exporter's textfile collector)")
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+
+	driver, err := cfg.openDriver()
+	if err != nil {
+		return err
+	}
+
+	// report the changes actually performed by Migrate
+	observer := &deployObserver{deployed: planJSON{Rollback: []string{}, Apply: []string{}}}
+	options := append(e.options(cfg), adapt.Observe(observer))
+	if *recordSnapshot {
+		options = append(options, adapt.RecordSchemaSnapshot())
+	}
+	var metrics *adapt.PrometheusMetrics
+	if len(*metricsFile) > 0 {
+		metrics = adapt.NewPrometheusMetrics()
+		options = append(options, adapt.CollectMetrics(metrics))
+	}
+	err = adapt.Migrate(cfg.executor, driver, cfg.sources(), options...)
+	if metrics != nil {
+		// failed migrations are written as well, as they are most important
+		// for alerting
+		if writeErr := writeMetricsFile(*metricsFile, metrics); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	p := observer.deployed
+	return e.output(cfg, p, func(w io.Writer) {
+		p.print(w, "reverted", "applied")
+	})
+}
+
+func runDown(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("down", cfg, true)
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	recordSnapshot := fs.Bool("record-snapshot", false, "record the schema snapshot checked by \"adapt drift\"")
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+
+	driver, err := cfg.openDriver()
+	if err != nil {
+		return err
+	}
+	options := e.options(cfg)
+	if *recordSnapshot {
+		options = append(options, adapt.RecordSchemaSnapshot())
+	}
+	reverted, err := adapt.Rollback(driver, cfg.sources(), *steps, options...)
+	if err != nil {
+		return err
+	}
+
+	result := make([]migrationJSON, 0, len(reverted))
+	for _, m := range reverted {
+		result = append(result, appliedJSON(m))
+	}
+	return e.output(cfg, result, func(w io.Writer) {
+		for _, m := range reverted {
+			_, _ = fmt.Fprintf(w, "reverted\t%s\n", m.ID)
+		}
+	})
+}
+
+func runBaseline(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("baseline", cfg, true)
+	to := fs.String("to", "", "id of the last migration to record as applied (required)")
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+	if len(*to) == 0 {
+		_, _ = fmt.Fprintln(e.stderr, "missing -to")
+		fs.Usage()
+		return errUsage
+	}
+
+	driver, err := cfg.openDriver()
+	if err != nil {
+		return err
+	}
+	err = adapt.Baseline(driver, cfg.sources(), *to, e.options(cfg)...)
+	if err != nil {
+		return err
+	}
+
+	return e.output(cfg, map[string]string{"baseline": *to}, func(w io.Writer) {
+		_, _ = fmt.Fprintf(w, "recorded baseline up to %s\n", *to)
+	})
+}
+
+// journalJSON is the JSON representation of an adapt.JournalEntry
+type journalJSON struct {
+	Kind        string    `json:"kind"`
+	MigrationID string    `json:"migration_id"`
+	Executor    string    `json:"executor"`
+	Created     time.Time `json:"created"`
+	Reason      string    `json:"reason"`
+	Detail      string    `json:"detail"`
+}
+
+func runRepair(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("repair", cfg, true)
+	by := fs.String("by", "", "name of the person performing the repair (required)")
+	reason := fs.String("reason", "", "reason of the repair (required)")
+	hashes := fs.Bool("hashes", false, "replace stored hashes with the local ones")
+	markFinished := fs.Bool("mark-finished", false, "mark unfinished migrations as finished")
+	deleteUnfinished := fs.Bool("delete-unfinished", false, "delete unfinished migrations")
+	only := fs.String("only", "", "comma separated migration ids to restrict the repair to")
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+
+	options := []adapt.RepairOption{
+		adapt.RepairBy(*by),
+		adapt.RepairReason(*reason),
+		adapt.RepairWithOptions(e.options(cfg)...),
+	}
+	if *hashes {
+		options = append(options, adapt.RepairHashes())
+	}
+	if *markFinished {
+		options = append(options, adapt.RepairMarkUnfinishedAsFinished())
+	}
+	if *deleteUnfinished {
+		options = append(options, adapt.RepairDeleteUnfinished())
+	}
+	if ids := splitList(*only); len(ids) > 0 {
+		options = append(options, adapt.RepairOnly(ids...))
+	}
+
+	driver, err := cfg.openDriver()
+	if err != nil {
+		return err
+	}
+	entries, err := adapt.Repair(driver, cfg.sources(), options...)
+	if err != nil {
+		return err
+	}
+
+	result := make([]journalJSON, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, journalJSON(*entry))
+	}
+	return e.output(cfg, result, func(w io.Writer) {
+		if len(entries) == 0 {
+			_, _ = fmt.Fprintln(w, "nothing to repair")
+			return
+		}
+		for _, entry := range entries {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Kind, entry.MigrationID, entry.Detail)
+		}
+	})
+}
+
+// driftJSON is the JSON representation of an adapt.DriftReport
+type driftJSON struct {
+	Drifted      bool      `json:"drifted"`
+	MigrationID  string    `json:"migration_id"`
+	Recorded     time.Time `json:"recorded"`
+	RecordedHash string    `json:"recorded_hash"`
+	LiveHash     string    `json:"live_hash"`
+}
+
+func runDrift(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("drift", cfg, true)
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+
+	driver, err := cfg.openDriver()
+	if err != nil {
+		return err
+	}
+	report, err := adapt.CheckDrift(driver, e.options(cfg)...)
+	if err != nil {
+		return err
+	}
+
+	err = e.output(cfg, driftJSON{
+		Drifted:      report.Drifted,
+		MigrationID:  report.MigrationID,
+		Recorded:     report.Recorded,
+		RecordedHash: report.RecordedHash,
+		LiveHash:     report.LiveHash,
+	}, func(w io.Writer) {
+		if report.Drifted {
+			_, _ = fmt.Fprintf(w, "schema drifted since %s was applied\nrecorded\t%s\nlive\t%s\n", report.MigrationID, report.RecordedHash, report.LiveHash)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "schema matches the snapshot recorded after %s\n", report.MigrationID)
+	})
+	if err != nil {
+		return err
+	}
+	if report.Drifted {
+		return fmt.Errorf("schema drifted")
+	}
+	return nil
+}
+
+func runValidate(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("validate", cfg, false)
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+
+	diagnostics, err := adapt.Validate(cfg.sources(), e.options(cfg)...)
+	if diagnostics == nil && err != nil {
+		return err
+	}
+
+	result := map[string]interface{}{"valid": err == nil, "diagnostics": append(adapt.Diagnostics{}, diagnostics...)}
+	outErr := e.output(cfg, result, func(w io.Writer) {
+		if len(diagnostics) == 0 {
+			_, _ = fmt.Fprintln(w, "all migrations are valid")
+			return
+		}
+		_, _ = fmt.Fprintln(w, "SEVERITY\tID\tCODE\tMESSAGE")
+		for _, d := range diagnostics {
+			id := d.MigrationID
+			if len(id) == 0 {
+				id = "-"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Severity, id, d.Code, d.Message)
+		}
+	})
+	if outErr != nil {
+		return outErr
+	}
+	if err != nil {
+		return fmt.Errorf("found %d error(s)", len(diagnostics.Errors()))
+	}
+	return nil
+}
+
+// namingSchemes maps the -order values to their matching naming scheme
+var namingSchemes = map[string]string{
+	"lexical":   "timestamp",
+	"timestamp": "timestamp",
+	"natural":   "numeric",
+	"flyway":    "flyway",
+}
+
+func runNew(e *env, args []string) error {
+	cfg := &config{}
+	fs := e.newFlagSet("new", cfg, false)
+	scheme := fs.String("scheme", "", "naming scheme: timestamp, numeric or flyway (defaults to the one matching -order)")
+	layout := fs.String("timestamp-layout", "", "Go time layout of the timestamp naming scheme (default 20060102_1504)")
+	width := fs.Int("width", 4, "zero-padded width of the numeric naming scheme")
+	hookPackage := fs.String("hook-package", "", "create a Go hook stub in this package instead of SQL files")
+	hookRegistry := fs.String("hook-registry", "migrations", "map variable the Go hook stub registers into")
+	if err := e.parse(fs, cfg, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		_, _ = fmt.Fprintln(e.stderr, "missing description")
+		fs.Usage()
+		return errUsage
+	}
+
+	if len(*scheme) == 0 {
+		*scheme = namingSchemes[cfg.order]
+	}
+	var options []adapt.ScaffoldOption
+	switch *scheme {
+	case "timestamp":
+		options = append(options, adapt.ScaffoldNaming(adapt.TimestampNaming(*layout)))
+	case "numeric":
+		options = append(options, adapt.ScaffoldNaming(adapt.NumericNaming(*width)))
+	case "flyway":
+		options = append(options, adapt.ScaffoldNaming(adapt.FlywayNaming()))
+	default:
+		_, _ = fmt.Fprintf(e.stderr, "invalid -scheme %q\n", *scheme)
+		fs.Usage()
+		return errUsage
+	}
+	if len(*hookPackage) > 0 {
+		options = append(options, adapt.ScaffoldGoHook(*hookPackage, *hookRegistry))
+	}
+
+	id, files, err := adapt.NewMigrationFiles(cfg.dir, strings.Join(fs.Args(), " "), options...)
+	if err != nil {
+		return err
+	}
+
+	return e.output(cfg, map[string]interface{}{"id": id, "files": files}, func(w io.Writer) {
+		for _, file := range files {
+			_, _ = fmt.Fprintf(w, "created\t%s\n", file)
+		}
+	})
+}
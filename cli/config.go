@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/harwoeck/adapt"
+)
+
+// config contains the flags shared by all commands
+type config struct {
+	driver      string
+	dsn         string
+	sqlDriver   string
+	dir         string
+	recursive   bool
+	executor    string
+	format      string
+	order       string
+	dialect     string
+	strict      bool
+	policy      bool
+	includeTags string
+	excludeTags string
+	verbose     bool
+}
+
+// defaultSQLDrivers maps the supported dialects to the database/sql driver
+// name used by default
+var defaultSQLDrivers = map[string]string{
+	"postgres": "postgres",
+	"mysql":    "mysql",
+	"sqlite":   "sqlite3",
+}
+
+var orders = map[string]adapt.Comparator{
+	"lexical":   adapt.LexicalOrder,
+	"natural":   adapt.NaturalOrder,
+	"timestamp": adapt.TimestampOrder,
+	"flyway":    adapt.FlywayOrder,
+}
+
+// newFlagSet creates a flag.FlagSet for the named command, which registers all
+// shared flags into cfg. withDriver controls whether driver flags are added.
+func (e *env) newFlagSet(name string, cfg *config, withDriver bool) *flag.FlagSet {
+	fs := flag.NewFlagSet("adapt "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+
+	if withDriver {
+		if sqlDriversRegistered() {
+			fs.StringVar(&cfg.driver, "driver", "", "storage driver: file, postgres, mysql or sqlite")
+			fs.StringVar(&cfg.sqlDriver, "sql-driver", "", "registered database/sql driver name (defaults to postgres, mysql or sqlite3)")
+		} else {
+			fs.StringVar(&cfg.driver, "driver", "", "storage driver: file (SQL databases require a binary that imports their database/sql driver, see package cli)")
+		}
+		fs.StringVar(&cfg.dsn, "dsn", "", "data source name of the database, or the JSON file for the file driver")
+		fs.StringVar(&cfg.executor, "executor", "adapt-cli", "executor name stored with applied migrations")
+	}
+	fs.StringVar(&cfg.dir, "dir", "./sql", "directory containing the migration files")
+	fs.BoolVar(&cfg.recursive, "recursive", false, "include migration files in subdirectories")
+	fs.StringVar(&cfg.format, "format", "text", "output format: text or json")
+	fs.StringVar(&cfg.order, "order", "lexical", "migration id ordering: lexical, natural, timestamp or flyway")
+	fs.StringVar(&cfg.dialect, "dialect", "", "SQL dialect of the migrations for dialect specific checks: postgres, mysql or sqlite (defaults to -driver)")
+	fs.BoolVar(&cfg.strict, "strict", false, "forbid applying migrations out of order")
+	fs.BoolVar(&cfg.policy, "policy", false, "block destructive statements not acknowledged with \"-- +adapt AllowDestructive\"")
+	fs.StringVar(&cfg.includeTags, "include-tags", "", "comma separated tags of migrations to include")
+	fs.StringVar(&cfg.excludeTags, "exclude-tags", "", "comma separated tags of migrations to exclude")
+	fs.BoolVar(&cfg.verbose, "v", false, "enable verbose logging to stderr")
+
+	return fs
+}
+
+// parse parses args into fs and validates the shared flags in cfg.
+func (e *env) parse(fs *flag.FlagSet, cfg *config, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.format != "text" && cfg.format != "json" {
+		_, _ = fmt.Fprintf(e.stderr, "invalid -format %q\n", cfg.format)
+		fs.Usage()
+		return errUsage
+	}
+	if _, ok := defaultSQLDrivers[cfg.dialect]; len(cfg.dialect) > 0 && !ok {
+		_, _ = fmt.Fprintf(e.stderr, "invalid -dialect %q\n", cfg.dialect)
+		fs.Usage()
+		return errUsage
+	}
+	if _, ok := orders[cfg.order]; !ok {
+		_, _ = fmt.Fprintf(e.stderr, "invalid -order %q\n", cfg.order)
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// options converts the shared flags into adapt options
+func (e *env) options(cfg *config) []adapt.Option {
+	level := slog.LevelWarn
+	if cfg.verbose {
+		level = slog.LevelDebug
+	}
+
+	options := []adapt.Option{
+		adapt.CustomLogger(slog.New(slog.NewTextHandler(e.stderr, &slog.HandlerOptions{Level: level}))),
+		adapt.OrderBy(orders[cfg.order]),
+	}
+	if dialect := cfg.sqlDialect(); len(dialect) > 0 {
+		options = append(options, adapt.SQLDialect(dialect))
+	}
+	if cfg.strict {
+		options = append(options, adapt.StrictOrdering())
+	}
+	if cfg.policy {
+		options = append(options, adapt.EnforcePolicy(adapt.DefaultPolicyRules()...))
+	}
+	if tags := splitList(cfg.includeTags); len(tags) > 0 {
+		options = append(options, adapt.IncludeTags(tags...))
+	}
+	if tags := splitList(cfg.excludeTags); len(tags) > 0 {
+		options = append(options, adapt.ExcludeTags(tags...))
+	}
+	return options
+}
+
+// sqlDialect returns the -dialect flag or the dialect of the -driver flag
+func (cfg *config) sqlDialect() adapt.Dialect {
+	if len(cfg.dialect) > 0 {
+		return adapt.Dialect(cfg.dialect)
+	}
+	if _, ok := defaultSQLDrivers[cfg.driver]; ok {
+		return adapt.Dialect(cfg.driver)
+	}
+	return ""
+}
+
+func (cfg *config) sources() adapt.SourceCollection {
+	var opts []adapt.FilesystemOption
+	if cfg.recursive {
+		opts = append(opts, adapt.FilesystemRecursive())
+	}
+	return adapt.SourceCollection{adapt.NewFilesystemSource(cfg.dir, opts...)}
+}
+
+// openDriver creates the adapt.Driver configured by the driver flags.
+func (cfg *config) openDriver() (adapt.Driver, error) {
+	if len(cfg.dsn) == 0 {
+		return nil, fmt.Errorf("missing -dsn")
+	}
+
+	if cfg.driver == "file" {
+		return adapt.NewFileDriver(cfg.dsn), nil
+	}
+
+	sqlDriver, ok := defaultSQLDrivers[cfg.driver]
+	if !ok || !sqlDriversRegistered() {
+		return nil, fmt.Errorf("unsupported -driver %q", cfg.driver)
+	}
+	if len(cfg.sqlDriver) > 0 {
+		sqlDriver = cfg.sqlDriver
+	}
+
+	db, err := sql.Open(sqlDriver, cfg.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database using database/sql driver %q (is it imported?): %w", sqlDriver, err)
+	}
+
+	switch cfg.driver {
+	case "postgres":
+		return adapt.NewPostgresDriver(db), nil
+	case "mysql":
+		return adapt.NewMySQLDriver(db), nil
+	default:
+		return adapt.NewSQLiteDriver(db), nil
+	}
+}
+
+// sqlDriversRegistered reports whether the binary registered any database/sql
+// driver. Without one the SQL storage drivers can't be used and aren't offered.
+func sqlDriversRegistered() bool {
+	return len(registeredSQLDrivers()) > 0
+}
+
+// registeredSQLDrivers is replaced by tests, as database/sql drivers can't be
+// unregistered
+var registeredSQLDrivers = sql.Drivers
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/harwoeck/adapt"
+)
+
+// output writes v as indented JSON or calls text with a tabwriter, depending on
+// the configured -format.
+func (e *env) output(cfg *config, v interface{}, text func(w io.Writer)) error {
+	if cfg.format == "json" {
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	text(tw)
+	return tw.Flush()
+}
+
+// migrationJSON is the JSON representation of a single migration
+type migrationJSON struct {
+	ID         string     `json:"id"`
+	State      string     `json:"state,omitempty"`
+	Hash       *string    `json:"hash,omitempty"`
+	Executor   string     `json:"executor,omitempty"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	Deployment string     `json:"deployment,omitempty"`
+}
+
+func appliedJSON(m *adapt.Migration) migrationJSON {
+	return migrationJSON{
+		ID:         m.ID,
+		Hash:       m.Hash,
+		Executor:   m.Executor,
+		Finished:   m.Finished,
+		Deployment: m.Deployment,
+	}
+}
+
+func availableJSON(m *adapt.AvailableMigration) migrationJSON {
+	return migrationJSON{
+		ID:   m.ID,
+		Hash: m.Hash,
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func printIDs(w io.Writer, action string, ids []string) {
+	for _, id := range ids {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", action, id)
+	}
+}
+
+// writeMetricsFile replaces filename with the current metrics. The file is
+// written to a temporary file first and renamed afterwards, so collectors
+// never read a partially written file.
+func writeMetricsFile(filename string, metrics *adapt.PrometheusMetrics) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	_, err = metrics.WriteTo(f)
+	if closeErr := f.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	// temporary files are only readable by their owner
+	if err = os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
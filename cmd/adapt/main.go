@@ -0,0 +1,13 @@
+// Command adapt operates migrations outside the application. See package
+// github.com/harwoeck/adapt/cli for details.
+package main
+
+import (
+	"os"
+
+	"github.com/harwoeck/adapt/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
+}
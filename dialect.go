@@ -0,0 +1,37 @@
+package adapt
+
+// Dialect identifies the SQL dialect migrations are written in. It enables
+// dialect specific checks of SqlStatementsSource migrations, like the hazards
+// reported by Validate.
+type Dialect string
+
+const (
+	// DialectPostgres is the dialect of NewPostgresDriver
+	DialectPostgres Dialect = "postgres"
+	// DialectMySQL is the dialect of NewMySQLDriver
+	DialectMySQL Dialect = "mysql"
+	// DialectSQLite is the dialect of NewSQLiteDriver
+	DialectSQLite Dialect = "sqlite"
+)
+
+// dialect returns the Dialect configured by SQLDialect or the one of the
+// built-in Driver implementations. It is empty when the Dialect is unknown.
+func (e *exec) dialect() Dialect {
+	if len(e.optDialect) > 0 {
+		return e.optDialect
+	}
+	if e.driver == nil {
+		return ""
+	}
+
+	switch e.driver.Name() {
+	case "driver_postgres":
+		return DialectPostgres
+	case "driver_mysql":
+		return DialectMySQL
+	case "driver_sqlite":
+		return DialectSQLite
+	default:
+		return ""
+	}
+}
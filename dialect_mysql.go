@@ -176,6 +176,21 @@ func (d *mysqlDriver) Healthy() error {
 		return err
 	}
 
+	createJournal := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+(
+    kind             VARCHAR(64)  NOT NULL,
+    migration_id     VARCHAR(255) NOT NULL,
+    executor         VARCHAR(255) NOT NULL,
+    created          TIMESTAMP(6) NOT NULL,
+    reason           TEXT         NOT NULL,
+    detail           TEXT         NOT NULL
+);`, d.journalTableName())
+	_, err = d.DB().Exec(createJournal)
+	if err != nil {
+		d.log.Error("failed to create or check if journal table exists", "error", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -207,6 +222,11 @@ func (d *mysqlDriver) SetMigrationToFinished(migrationID string) (query string,
 		[]interface{}{time.Now().UTC(), migrationID}
 }
 
+func (d *mysqlDriver) UpdateMigrationHash(migrationID string, hash *string) (query string, args []interface{}) {
+	return fmt.Sprintf("UPDATE %s SET hash=? WHERE id=?", d.tableName),
+		[]interface{}{hash, migrationID}
+}
+
 func (d *mysqlDriver) Close() error {
 	if !d.optDisableDBClose {
 		return d.db.Close()
@@ -230,6 +250,27 @@ func (d *mysqlDriver) UseGlobalTx() bool {
 	return true
 }
 
+func (d *mysqlDriver) journalTableName() string {
+	return d.tableName + "_journal"
+}
+
+func (d *mysqlDriver) AddJournalEntry(entry *JournalEntry) (query string, args []interface{}) {
+	return fmt.Sprintf("INSERT INTO %s (kind, migration_id, executor, created, reason, detail) VALUES (?, ?, ?, ?, ?, ?)", d.journalTableName()),
+		[]interface{}{entry.Kind, entry.MigrationID, entry.Executor, entry.Created, entry.Reason, entry.Detail}
+}
+
+func (d *mysqlDriver) ListJournalEntries() (query string) {
+	return fmt.Sprintf("SELECT kind, migration_id, executor, created, reason, detail FROM %s ORDER BY created", d.journalTableName())
+}
+
 func (d *mysqlDriver) DeleteMigration(migrationID string) (query string, args []interface{}) {
 	return fmt.Sprintf("DELETE FROM %s WHERE id=?", d.tableName), []interface{}{migrationID}
 }
+
+func (d *mysqlDriver) SchemaQueries() (queries []string) {
+	return []string{
+		`SELECT 'column', table_name, column_name, column_type, is_nullable, column_default, extra FROM information_schema.columns WHERE table_schema = DATABASE()`,
+		`SELECT 'index', table_name, index_name, non_unique, seq_in_index, column_name FROM information_schema.statistics WHERE table_schema = DATABASE()`,
+		`SELECT 'view', table_name, view_definition FROM information_schema.views WHERE table_schema = DATABASE()`,
+	}
+}
@@ -86,6 +86,21 @@ func (d *postgresDriver) Healthy() error {
 		return err
 	}
 
+	createJournal := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+(
+    kind             TEXT         NOT NULL,
+    migration_id     TEXT         NOT NULL,
+    executor         TEXT         NOT NULL,
+    created          TIMESTAMP(6) NOT NULL,
+    reason           TEXT         NOT NULL,
+    detail           TEXT         NOT NULL
+);`, d.journalTableName())
+	_, err = d.DB().Exec(createJournal)
+	if err != nil {
+		d.log.Error("failed to create or check if journal table exists", "error", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -118,6 +133,11 @@ func (d *postgresDriver) SetMigrationToFinished(migrationID string) (query strin
 		[]interface{}{time.Now().UTC(), migrationID}
 }
 
+func (d *postgresDriver) UpdateMigrationHash(migrationID string, hash *string) (query string, args []interface{}) {
+	return fmt.Sprintf("UPDATE %s SET hash=$1 WHERE id=$2", d.tableName),
+		[]interface{}{hash, migrationID}
+}
+
 func (d *postgresDriver) Close() error {
 	return d.db.Close()
 }
@@ -138,6 +158,30 @@ func (d *postgresDriver) UseGlobalTx() bool {
 	return true
 }
 
+func (d *postgresDriver) journalTableName() string {
+	return d.tableName + "_journal"
+}
+
+func (d *postgresDriver) AddJournalEntry(entry *JournalEntry) (query string, args []interface{}) {
+	return fmt.Sprintf("INSERT INTO %s (kind, migration_id, executor, created, reason, detail) VALUES ($1, $2, $3, $4, $5, $6)", d.journalTableName()),
+		[]interface{}{entry.Kind, entry.MigrationID, entry.Executor, entry.Created, entry.Reason, entry.Detail}
+}
+
+func (d *postgresDriver) ListJournalEntries() (query string) {
+	return fmt.Sprintf("SELECT kind, migration_id, executor, created, reason, detail FROM %s ORDER BY created", d.journalTableName())
+}
+
 func (d *postgresDriver) DeleteMigration(migrationID string) (query string, args []interface{}) {
 	return fmt.Sprintf("DELETE FROM %s WHERE id=$1", d.tableName), []interface{}{migrationID}
 }
+
+func (d *postgresDriver) SchemaQueries() (queries []string) {
+	return []string{
+		`SELECT 'column', table_schema, table_name, column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`,
+		// NOT NULL constraints are named after OIDs ("<nspoid>_<reloid>_<attnum>_not_null"),
+		// which differ between databases. They are covered by is_nullable.
+		`SELECT 'constraint', table_schema, table_name, constraint_name, constraint_type FROM information_schema.table_constraints WHERE table_schema NOT IN ('pg_catalog', 'information_schema') AND constraint_name !~ '^[0-9]+_[0-9]+_[0-9]+_not_null$'`,
+		`SELECT 'index', schemaname, tablename, indexname, indexdef FROM pg_indexes WHERE schemaname NOT IN ('pg_catalog', 'information_schema')`,
+		`SELECT 'view', table_schema, table_name, view_definition FROM information_schema.views WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`,
+	}
+}
@@ -102,6 +102,21 @@ func (d *sqliteDriver) Healthy() error {
 		return err
 	}
 
+	createJournal := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+(
+    kind             TEXT     NOT NULL,
+    migration_id     TEXT     NOT NULL,
+    executor         TEXT     NOT NULL,
+    created          DATETIME NOT NULL,
+    reason           TEXT     NOT NULL,
+    detail           TEXT     NOT NULL
+)`, d.journalTableName())
+	_, err = d.DB().Exec(createJournal)
+	if err != nil {
+		d.log.Error("failed to create or check if journal table exists", "error", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -133,6 +148,11 @@ func (d *sqliteDriver) SetMigrationToFinished(migrationID string) (query string,
 		[]interface{}{time.Now().UTC(), migrationID}
 }
 
+func (d *sqliteDriver) UpdateMigrationHash(migrationID string, hash *string) (query string, args []interface{}) {
+	return fmt.Sprintf("UPDATE %s SET hash=? WHERE id=?", d.tableName),
+		[]interface{}{hash, migrationID}
+}
+
 func (d *sqliteDriver) Close() error {
 	return d.db.Close()
 }
@@ -153,6 +173,25 @@ func (d *sqliteDriver) UseGlobalTx() bool {
 	return false
 }
 
+func (d *sqliteDriver) journalTableName() string {
+	return d.tableName + "_journal"
+}
+
+func (d *sqliteDriver) AddJournalEntry(entry *JournalEntry) (query string, args []interface{}) {
+	return fmt.Sprintf("INSERT INTO %s (kind, migration_id, executor, created, reason, detail) VALUES (?, ?, ?, ?, ?, ?)", d.journalTableName()),
+		[]interface{}{entry.Kind, entry.MigrationID, entry.Executor, entry.Created, entry.Reason, entry.Detail}
+}
+
+func (d *sqliteDriver) ListJournalEntries() (query string) {
+	return fmt.Sprintf("SELECT kind, migration_id, executor, created, reason, detail FROM %s ORDER BY created", d.journalTableName())
+}
+
 func (d *sqliteDriver) DeleteMigration(migrationID string) (query string, args []interface{}) {
 	return fmt.Sprintf("DELETE FROM %s WHERE id=?", d.tableName), []interface{}{migrationID}
 }
+
+func (d *sqliteDriver) SchemaQueries() (queries []string) {
+	return []string{
+		`SELECT type, name, tbl_name, sql FROM sqlite_master WHERE name NOT LIKE 'sqlite_%'`,
+	}
+}
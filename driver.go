@@ -48,3 +48,30 @@ type Driver interface {
 	// when an error is encountered somewhere or the library panics
 	Close() error
 }
+
+// DriverHashUpdater is an optional extension of Driver. When implemented adapt
+// can replace the stored hash of an applied migration, e.g. to transparently
+// upgrade hashes produced by an older hash algorithm.
+type DriverHashUpdater interface {
+	Driver
+	// UpdateMigrationHash must replace the stored hash of the migration with
+	// migrationID.
+	UpdateMigrationHash(migrationID string, hash *string) error
+}
+
+// hashUpdateSupporter is implemented by driver adapters, like the one returned
+// by FromSqlStatementsDriver, that always provide UpdateMigrationHash but can
+// only perform it when the wrapped driver supports it.
+type hashUpdateSupporter interface {
+	supportsHashUpdates() bool
+}
+
+// asHashUpdater returns driver as DriverHashUpdater, if it implements it and
+// actually supports updating hashes.
+func asHashUpdater(driver Driver) (DriverHashUpdater, bool) {
+	updater, ok := driver.(DriverHashUpdater)
+	if s, isAdapter := driver.(hashUpdateSupporter); ok && isAdapter {
+		ok = s.supportsHashUpdates()
+	}
+	return updater, ok
+}
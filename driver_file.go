@@ -59,6 +59,7 @@ func (d *fileDriver) Init(log *slog.Logger) error {
 
 type fileDriverStorage struct {
 	Migrations []*Migration
+	Journal    []*JournalEntry `json:",omitempty"`
 }
 
 func (d *fileDriver) readStorage() (*fileDriverStorage, error) {
@@ -203,6 +204,64 @@ func (d *fileDriver) SetMigrationToFinished(migrationID string) error {
 	return d.writeStorage(s)
 }
 
+func (d *fileDriver) UpdateMigrationHash(migrationID string, hash *string) error {
+	s, err := d.readStorage()
+	if err != nil {
+		return err
+	}
+
+	var set bool
+	for _, item := range s.Migrations {
+		if item.ID == migrationID {
+			item.Hash = hash
+			set = true
+			break
+		}
+	}
+	if !set {
+		d.log.Error("migration not found", "migration_id", migrationID)
+		return fmt.Errorf("adapt.fileDriver: migration missing")
+	}
+
+	return d.writeStorage(s)
+}
+
+func (d *fileDriver) RemoveMigration(migrationID string) error {
+	s, err := d.readStorage()
+	if err != nil {
+		return err
+	}
+
+	for i, item := range s.Migrations {
+		if item.ID == migrationID {
+			s.Migrations = append(s.Migrations[:i], s.Migrations[i+1:]...)
+			return d.writeStorage(s)
+		}
+	}
+
+	d.log.Error("migration not found", "migration_id", migrationID)
+	return fmt.Errorf("adapt.fileDriver: migration missing")
+}
+
+func (d *fileDriver) AddJournalEntry(entry *JournalEntry) error {
+	s, err := d.readStorage()
+	if err != nil {
+		return err
+	}
+
+	s.Journal = append(s.Journal, entry)
+	return d.writeStorage(s)
+}
+
+func (d *fileDriver) ListJournalEntries() ([]*JournalEntry, error) {
+	s, err := d.readStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Journal, nil
+}
+
 func (d *fileDriver) Close() error {
 	return nil
 }
@@ -3,7 +3,10 @@ package adapt
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 )
 
@@ -75,6 +78,29 @@ type SqlStatementsDriver interface {
 	DeleteMigration(migrationID string) (query string, args []interface{})
 }
 
+// SqlStatementsHashUpdater is an optional extension of SqlStatementsDriver.
+// When implemented the DatabaseDriver returned by FromSqlStatementsDriver can
+// replace stored hashes (see DriverHashUpdater).
+type SqlStatementsHashUpdater interface {
+	// UpdateMigrationHash must return a database query and it's corresponding
+	// args in order to replace the hash of the migration with migrationID.
+	UpdateMigrationHash(migrationID string, hash *string) (query string, args []interface{})
+}
+
+// SqlStatementsJournal is an optional extension of SqlStatementsDriver. When
+// implemented the DatabaseDriver returned by FromSqlStatementsDriver stores
+// JournalEntry records (see DriverJournal). Healthy is responsible for
+// creating the journal-table.
+type SqlStatementsJournal interface {
+	// AddJournalEntry must return a database query and it's corresponding args
+	// that insert the passed JournalEntry into the journal-table.
+	AddJournalEntry(entry *JournalEntry) (query string, args []interface{})
+	// ListJournalEntries must return a database query that selects all
+	// JournalEntry data ordered by Created in the following order: Kind,
+	// MigrationID, Executor, Created, Reason, Detail.
+	ListJournalEntries() (query string)
+}
+
 // FromSqlStatementsDriver converts a SqlStatementsDriver to a full DatabaseDriver
 // by wrapping it in an internal adapter that handles all sql.DB operations
 // according to the features specified by SqlStatementsDriver
@@ -85,11 +111,12 @@ func FromSqlStatementsDriver(driver SqlStatementsDriver) DatabaseDriver {
 }
 
 type stmtDriver struct {
-	driver   SqlStatementsDriver
-	log      *slog.Logger
-	target   DBTarget
-	tx       *sql.Tx
-	rollback bool
+	driver      SqlStatementsDriver
+	log         *slog.Logger
+	target      DBTarget
+	tx          *sql.Tx
+	rollback    bool
+	onStatement func(event StatementEvent)
 }
 
 func (d *stmtDriver) Name() string {
@@ -124,6 +151,10 @@ func (d *stmtDriver) Init(log *slog.Logger) error {
 	return nil
 }
 
+func (d *stmtDriver) observeStatements(fn func(event StatementEvent)) {
+	d.onStatement = fn
+}
+
 func (d *stmtDriver) Healthy() error {
 	return d.driver.Healthy()
 }
@@ -215,16 +246,20 @@ func (d *stmtDriver) AddMigration(m *Migration) error {
 }
 
 func (d *stmtDriver) Migrate(migration *ParsedMigration, beforeFinish func(target DBTarget) error) error {
-	for _, s := range migration.Stmts {
+	for idx, s := range migration.Stmts {
 		d.log.Debug("executing statement", "statement", s)
 
 		started := time.Now()
-		if _, err := d.target.Exec(s); err != nil {
+		_, err := d.target.Exec(s)
+		end := time.Now()
+		if d.onStatement != nil {
+			d.onStatement(StatementEvent{Statement: s, Index: idx, InTx: d.tx != nil, Duration: end.Sub(started), Err: err})
+		}
+		if err != nil {
 			d.log.Error("failed executing statement", "statement", s, "error", err)
 			d.rollback = true
 			return err
 		}
-		end := time.Now()
 
 		d.log.Debug("executing statement took", "duration", end.Sub(started))
 	}
@@ -254,6 +289,25 @@ func (d *stmtDriver) SetMigrationToFinished(migrationID string) error {
 	return err
 }
 
+func (d *stmtDriver) supportsHashUpdates() bool {
+	_, ok := d.driver.(SqlStatementsHashUpdater)
+	return ok
+}
+
+func (d *stmtDriver) UpdateMigrationHash(migrationID string, hash *string) error {
+	updater, ok := d.driver.(SqlStatementsHashUpdater)
+	if !ok {
+		return fmt.Errorf("adapt: driver %q doesn't support updating hashes: %w", d.driver.Name(), errors.ErrUnsupported)
+	}
+
+	query, args := updater.UpdateMigrationHash(migrationID, hash)
+	_, err := d.target.Exec(query, args...)
+	if err != nil {
+		d.rollback = true
+	}
+	return err
+}
+
 func (d *stmtDriver) Close() error {
 	// if tx is not nil, we started a tx and need to commit/rollback it
 	if d.tx != nil {
@@ -295,6 +349,62 @@ func (d *stmtDriver) TxBeginOpts() (ctx context.Context, opts *sql.TxOptions) {
 	return d.driver.TxBeginOpts()
 }
 
+func (d *stmtDriver) RemoveMigration(migrationID string) error {
+	return d.DeleteMigration(migrationID, d.target)
+}
+
+func (d *stmtDriver) supportsJournal() bool {
+	_, ok := d.driver.(SqlStatementsJournal)
+	return ok
+}
+
+func (d *stmtDriver) AddJournalEntry(entry *JournalEntry) error {
+	journal, ok := d.driver.(SqlStatementsJournal)
+	if !ok {
+		return fmt.Errorf("adapt: driver %q doesn't support a journal: %w", d.driver.Name(), errors.ErrUnsupported)
+	}
+
+	query, args := journal.AddJournalEntry(entry)
+	_, err := d.target.Exec(query, args...)
+	if err != nil {
+		d.rollback = true
+	}
+	return err
+}
+
+func (d *stmtDriver) ListJournalEntries() ([]*JournalEntry, error) {
+	journal, ok := d.driver.(SqlStatementsJournal)
+	if !ok {
+		return nil, fmt.Errorf("adapt: driver %q doesn't support a journal: %w", d.driver.Name(), errors.ErrUnsupported)
+	}
+
+	var entries []*JournalEntry
+
+	rows, err := d.target.Query(journal.ListJournalEntries())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		e := &JournalEntry{}
+		err = rows.Scan(&e.Kind, &e.MigrationID, &e.Executor, &e.Created, &e.Reason, &e.Detail)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	err = rows.Err()
+	if err != nil {
+		d.rollback = true
+		return nil, err
+	}
+
+	return entries, nil
+}
+
 func (d *stmtDriver) DeleteMigration(migrationID string, target DBTarget) error {
 	query, args := d.driver.DeleteMigration(migrationID)
 	_, err := target.Exec(query, args...)
@@ -303,3 +413,59 @@ func (d *stmtDriver) DeleteMigration(migrationID string, target DBTarget) error
 	}
 	return err
 }
+
+func (d *stmtDriver) InspectSchema() (*SchemaSnapshot, error) {
+	inspector, ok := d.driver.(SqlStatementsSchemaInspector)
+	if !ok {
+		return nil, fmt.Errorf("adapt: driver %q doesn't support schema inspection: %w", d.driver.Name(), errors.ErrUnsupported)
+	}
+
+	var objects []string
+	for _, query := range inspector.SchemaQueries() {
+		rows, err := queryRows(d.target, query)
+		if err != nil {
+			d.rollback = true
+			return nil, err
+		}
+		objects = append(objects, rows...)
+	}
+	return NewSchemaSnapshot(objects), nil
+}
+
+// queryRows renders every row returned by query as a single line of tab
+// separated values
+func queryRows(target DBTarget, query string) ([]string, error) {
+	rows, err := target.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var lines []string
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		fields := make([]string, len(values))
+		for i, v := range values {
+			fields[i] = "NULL"
+			if v.Valid {
+				fields[i] = v.String
+			}
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	return lines, rows.Err()
+}
@@ -0,0 +1,50 @@
+package adapt
+
+import "testing"
+
+// baseDialect only implements the required methods of SqlStatementsDriver
+type baseDialect struct {
+	SqlStatementsDriver
+}
+
+func Test_asHashUpdater(t *testing.T) {
+	postgres := NewPostgresDriver(nil).(*stmtDriver).driver
+
+	tests := []struct {
+		name   string
+		driver Driver
+		want   bool
+	}{
+		{"file driver", NewFileDriver("test.json"), true},
+		{"sql statements driver", FromSqlStatementsDriver(postgres), true},
+		{"sql statements driver without hash updates", FromSqlStatementsDriver(baseDialect{postgres}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := asHashUpdater(tt.driver); got != tt.want {
+				t.Errorf("asHashUpdater() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_asJournal(t *testing.T) {
+	postgres := NewPostgresDriver(nil).(*stmtDriver).driver
+
+	tests := []struct {
+		name   string
+		driver Driver
+		want   bool
+	}{
+		{"file driver", NewFileDriver("test.json"), true},
+		{"sql statements driver", FromSqlStatementsDriver(postgres), true},
+		{"sql statements driver without journal", FromSqlStatementsDriver(baseDialect{postgres}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := asJournal(tt.driver); got != tt.want {
+				t.Errorf("asJournal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
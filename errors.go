@@ -4,3 +4,5 @@ import "errors"
 
 var ErrIntegrityProtection = errors.New("adapt: abort due to integrity protection rules. See log output for details")
 var ErrInvalidSource = errors.New("adapt: source violated a precondition. See log output for details")
+var ErrNoSchemaSnapshot = errors.New("adapt: no schema snapshot recorded. See RecordSchemaSnapshot")
+var ErrPolicyViolation = errors.New("adapt: migration violates the enforced policy. See log output for details")
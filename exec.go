@@ -3,6 +3,7 @@ package adapt
 import (
 	"log/slog"
 	"os"
+	"time"
 )
 
 type exec struct {
@@ -13,6 +14,20 @@ type exec struct {
 
 	optDisableDriverLocks         bool
 	optDisableHashIntegrityChecks bool
+	optDisableHashUpgrades        bool
+	optNormalizedHashes           bool
+	optManifest                   *Manifest
+	optHookChangePolicy           HookChangePolicy
+	optBaselineOnEmptyMeta        string
+	optOrder                      Comparator
+	optStrictOrdering             bool
+	optTags                       tagFilter
+	optIDValidators               []IDValidator
+	optDialect                    Dialect
+	optPolicy                     []PolicyRule
+	optRecordSchemaSnapshot       bool
+	optObservers                  []Observer
+	optMetrics                    Metrics
 
 	driverIsDatabaseDriver                bool
 	driverAsDatabaseDriver                DatabaseDriver
@@ -20,9 +35,18 @@ type exec struct {
 	driverAsDatabaseDriverCustomMigration DatabaseDriverCustomMigration
 
 	available          []*AvailableMigration
+	skipped            []*AvailableMigration
+	repeatable         []*AvailableMigration
 	driverLockAcquired bool
+	driverLockSince    time.Time
 	applied            []*Migration
+	appliedRepeatable  []*Migration
 	unknownApplied     []*Migration
+	replacedApplied    []*Migration
+	deployed           []string
+	reverted           []string
+	currentMigration   string
+	failedStage        Stage
 }
 
 func newExec(executor string, driver Driver, sources SourceCollection, options ...Option) (*exec, error) {
@@ -32,6 +56,7 @@ func newExec(executor string, driver Driver, sources SourceCollection, options .
 		driver:   driver,
 		sources:  sources,
 		log:      slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		optOrder: LexicalOrder,
 	}
 
 	// apply options
@@ -55,33 +80,64 @@ func newExec(executor string, driver Driver, sources SourceCollection, options .
 		}
 	}
 
+	// report statements executed by the driver itself
+	if so, ok := driver.(statementObserver); ok {
+		so.observeStatements(e.notifyStatement)
+	}
+
 	return e, nil
 }
 
-func (e *exec) run() (err error) {
+func (e *exec) run() error {
+	return e.runWith(e.stageStart)
+}
+
+// runWith runs all stages needed to prepare the local and remote state and
+// afterwards calls stage while the driver lock is held.
+func (e *exec) runWith(stage func() error) error {
+	return e.runLocked(func() error {
+		err := e.runStage(StagePrepareRemote, e.stagePrepareRemote)
+		if err != nil {
+			return err
+		}
+		e.reportPendingMigrations()
+		defer e.reportPendingMigrations()
+
+		return stage()
+	})
+}
+
+// runLocked runs all stages needed to prepare the local state and afterwards
+// calls stage while the driver lock is held. Unlike runWith it doesn't load
+// and check the remote state, which is needed by operations like Repair that
+// operate on a meta-storage Migrate refuses to work with.
+func (e *exec) runLocked(stage func() error) (err error) {
 	defer func() {
-		closeErr := e.stageClose()
+		closeErr := e.runStage(StageClose, e.stageClose)
 		if closeErr != nil && err == nil {
 			err = closeErr
 		}
+		if err != nil {
+			e.reportFailure()
+		}
 	}()
 
-	err = e.stageInit()
+	err = e.runStage(StageInit, e.stageInit)
 	if err != nil {
 		return err
 	}
 
-	err = e.stageHealthCheck()
+	err = e.runStage(StageHealthCheck, e.stageHealthCheck)
 	if err != nil {
 		return err
 	}
 
-	err = e.stagePrepareLocal()
+	err = e.runStage(StagePrepareLocal, e.stagePrepareLocal)
 	if err != nil {
 		return err
 	}
 
-	err = e.acquireDriverLock()
+	err = e.runStage(StageLock, e.acquireDriverLock)
 	if err != nil {
 		return err
 	}
@@ -94,15 +150,5 @@ func (e *exec) run() (err error) {
 		}()
 	}
 
-	err = e.stagePrepareRemote()
-	if err != nil {
-		return err
-	}
-
-	err = e.stageStart()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return stage()
 }
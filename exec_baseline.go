@@ -0,0 +1,107 @@
+package adapt
+
+import (
+	"fmt"
+)
+
+func (e *exec) stageBaseline(upToID string) error {
+	e.log.Debug("baseline", "up_to_id", upToID)
+
+	migrations, err := e.baselineMigrations(upToID)
+	if err != nil {
+		return err
+	}
+
+	err = e.recordBaseline(migrations)
+	if err != nil {
+		return err
+	}
+
+	e.log.Info("baseline successful", "recorded_amount", len(migrations))
+	return nil
+}
+
+// baselineMigrations returns all available migrations up to (and including)
+// upToID that aren't applied yet. It doesn't change anything.
+func (e *exec) baselineMigrations(upToID string) ([]*AvailableMigration, error) {
+	if e.findAvailable(upToID) == nil {
+		e.log.Error("baseline migration id isn't available", "migration_id", upToID)
+		return nil, fmt.Errorf("adapt: baseline migration id %q isn't available", upToID)
+	}
+
+	appliedIDs := make(map[string]struct{}, len(e.applied))
+	for _, a := range e.applied {
+		appliedIDs[a.ID] = struct{}{}
+	}
+
+	var migrations []*AvailableMigration
+	for _, migration := range e.available {
+		if _, ok := appliedIDs[migration.ID]; !ok {
+			migrations = append(migrations, migration)
+		}
+
+		if migration.ID == upToID {
+			break
+		}
+	}
+	return migrations, nil
+}
+
+// recordBaseline records all migrations without executing them under a single
+// baseline deployment.
+func (e *exec) recordBaseline(migrations []*AvailableMigration) error {
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	dID, err := genBaselineDeploymentID()
+	if err != nil {
+		e.log.Error("failed to generate deployment id", "error", err)
+		return err
+	}
+
+	for dOrder, migration := range migrations {
+		err = e.recordWithoutExecution(migration, dID, dOrder)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func genBaselineDeploymentID() (string, error) {
+	dID, err := genDeploymentID()
+	if err != nil {
+		return "", err
+	}
+	return BaselineDeploymentPrefix + dID, nil
+}
+
+// recordWithoutExecution adds the migration to the driver's meta-storage and
+// sets it to finished without executing it. The recorded migration is marked
+// as baseline (see Migration.IsBaseline) and added to the applied migrations.
+func (e *exec) recordWithoutExecution(migration *AvailableMigration, deployment string, deploymentOrder int) error {
+	meta, err := convertToMigration(migration, BaselineExecutor, deployment, deploymentOrder, e.log)
+	if err != nil {
+		return err
+	}
+
+	err = e.driver.AddMigration(meta)
+	if err != nil {
+		e.log.Error("failed to add baseline migration", "migration_id", migration.ID, "error", err)
+		return err
+	}
+	err = e.driver.SetMigrationToFinished(migration.ID)
+	if err != nil {
+		e.log.Error("failed to set baseline migration to finished", "migration_id", migration.ID, "error", err)
+		return err
+	}
+
+	e.log.Info("recorded migration as baseline without executing it", "migration_id", migration.ID)
+	e.applied = append(e.applied, meta)
+
+	// keep applied migrations in the same order as available migrations
+	sortApplied(e.applied, e.optOrder)
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package adapt
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// orderByDependencies orders available migrations topologically by their
+// DependsOn declarations. Migrations without a relationship keep their relative
+// order, so the result equals the input when no dependencies are declared.
+// Dependencies on IDs that aren't available are ignored here, as they might
+// already be applied. They are checked by checkDependencies instead.
+func orderByDependencies(available []*AvailableMigration, log *slog.Logger) ([]*AvailableMigration, error) {
+	providers := dependencyProviders(available)
+
+	for _, am := range available {
+		if dep, ok := selfDependency(am, providers); ok {
+			log.Error("migration depends on itself", "migration_id", am.ID, "depends_on", dep)
+			return nil, fmt.Errorf("adapt: dependency cycle: migration %q depends on itself through %q: %w", am.ID, dep, ErrInvalidSource)
+		}
+	}
+
+	ordered := make([]*AvailableMigration, 0, len(available))
+	emitted := make(map[string]bool, len(available))
+	remaining := available
+
+	for len(remaining) > 0 {
+		var next []*AvailableMigration
+		progress := false
+
+		for _, am := range remaining {
+			// only emit a migration once all of its available dependencies were
+			// emitted. Afterwards restart from the front, so that the original
+			// order is kept as far as possible.
+			if progress || !dependenciesEmitted(am, providers, emitted) {
+				next = append(next, am)
+				continue
+			}
+
+			ordered = append(ordered, am)
+			emitted[am.ID] = true
+			progress = true
+		}
+
+		if !progress {
+			ids := make([]string, 0, len(next))
+			for _, am := range next {
+				ids = append(ids, am.ID)
+			}
+			log.Error("migrations contain a dependency cycle", "migration_ids", ids)
+			return nil, fmt.Errorf("adapt: dependency cycle between migrations: %s: %w", strings.Join(ids, ", "), ErrInvalidSource)
+		}
+
+		remaining = next
+	}
+
+	return ordered, nil
+}
+
+// selfDependency returns the first dependency of am, which is provided by am
+// itself, either by its own ID or an ID it replaces
+func selfDependency(am *AvailableMigration, providers map[string]string) (string, bool) {
+	for _, dep := range am.DependsOn {
+		if providers[dep] == am.ID {
+			return dep, true
+		}
+	}
+	return "", false
+}
+
+func dependenciesEmitted(am *AvailableMigration, providers map[string]string, emitted map[string]bool) bool {
+	for _, dep := range am.DependsOn {
+		provider, ok := providers[dep]
+		if !ok {
+			continue
+		}
+		if !emitted[provider] {
+			return false
+		}
+	}
+	return true
+}
+
+// dependencyProviders maps every ID that can be depended on to the ID of the
+// available migration providing it. Squashed migrations provide all IDs they
+// replace.
+func dependencyProviders(available []*AvailableMigration) map[string]string {
+	providers := make(map[string]string, len(available))
+	for _, am := range available {
+		for _, replaced := range am.Replaces {
+			providers[replaced] = am.ID
+		}
+	}
+	for _, am := range available {
+		providers[am.ID] = am.ID
+	}
+	return providers
+}
+
+// checkDependencies verifies that every needed migration only depends on
+// migrations that are either already applied or applied in front of it within
+// needed.
+func checkDependencies(applied []*Migration, available []*AvailableMigration, needed []*AvailableMigration, log *slog.Logger) error {
+	providers := dependencyProviders(available)
+
+	satisfied := make(map[string]bool, len(applied)+len(needed))
+	for _, a := range applied {
+		satisfied[a.ID] = true
+	}
+
+	for _, am := range needed {
+		for _, dep := range am.DependsOn {
+			if satisfied[dep] {
+				continue
+			}
+			if provider, ok := providers[dep]; ok && satisfied[provider] {
+				continue
+			}
+
+			log.Error("migration depends on a migration that isn't applied. Refusing to apply it",
+				"migration_id", am.ID, "depends_on", dep)
+			return fmt.Errorf("adapt: migration %q depends on %q, which isn't applied: %w", am.ID, dep, ErrIntegrityProtection)
+		}
+		satisfied[am.ID] = true
+	}
+
+	return nil
+}
@@ -12,6 +12,15 @@ func (e *exec) stageInit() error {
 	}
 
 	// init sources
+	if err := e.initSources(); err != nil {
+		return err
+	}
+
+	e.log.Info("init successful")
+	return nil
+}
+
+func (e *exec) initSources() error {
 	for idx, src := range e.sources {
 		if err := src.Init(e.log); err != nil {
 			e.log.Error("failed to init source", "source_index", idx, "error", err)
@@ -27,6 +36,5 @@ func (e *exec) stageInit() error {
 		}
 	}
 
-	e.log.Info("init successful")
 	return nil
 }
@@ -0,0 +1,72 @@
+package adapt
+
+import "log/slog"
+
+// verifyAgainstManifest checks that every migration that is about to be applied
+// is pinned by the Manifest with an equal hash. For SqlStatementsSource
+// migrations the Down part must match the pinned ManifestEntry.DownHash as
+// well, as it is stored and executed during rollbacks. Migrations without a
+// hash (like Hooks) cannot be verified and are therefore refused.
+func verifyAgainstManifest(needed []*AvailableMigration, manifest *Manifest, log *slog.Logger) error {
+	ok := true
+	for _, m := range needed {
+		entry := manifest.Entry(m.ID)
+		switch {
+		case entry == nil:
+			log.Error("migration isn't approved by signed manifest", "migration_id", m.ID)
+			ok = false
+		case m.Hash == nil:
+			log.Error("migration doesn't provide a hash and cannot be verified against signed manifest", "migration_id", m.ID)
+			ok = false
+		case !hashMatches(m, entry.Hash):
+			log.Error("hash of migration differs from signed manifest",
+				"migration_id", m.ID, "local_hash", *m.Hash, "manifest_hash", entry.Hash)
+			ok = false
+		default:
+			downOK, err := downMatches(m, entry.DownHash, log)
+			if err != nil {
+				return err
+			}
+			ok = ok && downOK
+		}
+	}
+	if !ok {
+		return ErrIntegrityProtection
+	}
+
+	log.Info("verified needed migrations against signed manifest", "migrations_amount", len(needed))
+	return nil
+}
+
+func hashMatches(m *AvailableMigration, hash string) bool {
+	if m.ParsedUp != nil {
+		return m.ParsedUp.MatchesHash(hash)
+	}
+	return *m.Hash == hash
+}
+
+// downMatches reports whether the Down part of m matches hash. Migrations
+// that aren't provided by a SqlStatementsSource don't have a Down part.
+func downMatches(m *AvailableMigration, hash string, log *slog.Logger) (bool, error) {
+	src, ok := m.Source.(SqlStatementsSource)
+	if !ok {
+		return true, nil
+	}
+
+	down, err := src.GetParsedDownMigration(m.ID)
+	if err != nil {
+		log.Error("failed to get parsed down migration from SqlStatementsSource", "migration_id", m.ID, "error", err)
+		return false, err
+	}
+
+	switch {
+	case down == nil && len(hash) > 0:
+		log.Error("down migration approved by signed manifest is missing", "migration_id", m.ID)
+		return false, nil
+	case down != nil && !down.MatchesHash(hash):
+		log.Error("hash of down migration differs from signed manifest",
+			"migration_id", m.ID, "local_hash", *down.Hash(), "manifest_hash", hash)
+		return false, nil
+	}
+	return true, nil
+}
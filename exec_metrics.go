@@ -0,0 +1,62 @@
+package adapt
+
+func outcomeOf(err error) Outcome {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// metricsObserver translates the events of an operation into Metrics
+type metricsObserver struct {
+	NopObserver
+	metrics Metrics
+}
+
+func (o *metricsObserver) OnMigrationEnd(event MigrationEvent) {
+	o.metrics.ObserveMigration(MetricLabels{Driver: event.Driver, Executor: event.Executor}, outcomeOf(event.Err), event.Duration)
+}
+
+func (o *metricsObserver) OnStatement(event StatementEvent) {
+	o.metrics.ObserveStatement(MetricLabels{Driver: event.Driver, Executor: event.Executor}, outcomeOf(event.Err), event.Duration)
+}
+
+// OnLockAcquired records the wait of failed attempts as well, as they show
+// contention of the lock
+func (o *metricsObserver) OnLockAcquired(event LockEvent) {
+	o.metrics.ObserveLockWait(MetricLabels{Driver: event.Driver, Executor: event.Executor}, event.Wait)
+}
+
+// reportPendingMigrations counts the pending migrations like Status does.
+// Migrations applied by this operation aren't pending anymore.
+func (e *exec) reportPendingMigrations() {
+	if e.optMetrics == nil {
+		return
+	}
+
+	deployed := make(map[string]bool, len(e.deployed))
+	for _, id := range e.deployed {
+		deployed[id] = true
+	}
+
+	statuses, err := e.statuses()
+	if err != nil {
+		e.log.Debug("unable to resolve statuses. Skipping pending migrations metric", "error", err)
+		return
+	}
+
+	var pending int
+	for _, s := range statuses {
+		if s.State == StatusPending && !deployed[s.ID] {
+			pending++
+		}
+	}
+	e.optMetrics.SetPendingMigrations(e.driverName(), pending)
+}
+
+func (e *exec) reportFailure() {
+	if e.optMetrics == nil {
+		return
+	}
+	e.optMetrics.IncFailures(MetricLabels{Driver: e.driverName(), Executor: e.executor}, e.failedStage)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"strings"
 	"time"
 )
 
@@ -21,12 +22,38 @@ func (e *exec) stageMigrate() error {
 	}
 
 	// find all needed migrations
-	needed := findNeededMigrations(e.applied, e.available, e.log)
-	if len(needed) == 0 {
+	needed, err := findNeededMigrations(e.applied, e.available, e.optStrictOrdering, e.log)
+	if err != nil {
+		return err
+	}
+	repeatable := e.changedRepeatable()
+	if len(needed) == 0 && len(repeatable) == 0 {
 		e.log.Info("all migrations already applied. everything up-to-date")
 		return nil
 	}
 
+	// verify dependencies of needed migrations are satisfied
+	err = checkDependencies(e.applied, e.available, needed, e.log)
+	if err != nil {
+		return err
+	}
+
+	// verify needed migrations were approved by the signed manifest
+	if e.optManifest != nil {
+		err = verifyAgainstManifest(append(needed[:len(needed):len(needed)], repeatable...), e.optManifest, e.log)
+		if err != nil {
+			return err
+		}
+	}
+
+	// verify needed migrations don't contain unacknowledged dangerous statements
+	if len(e.optPolicy) > 0 {
+		err = enforcePolicy(upPolicyTargets(append(needed[:len(needed):len(needed)], repeatable...)), e.dialect(), e.optPolicy, e.log)
+		if err != nil {
+			return err
+		}
+	}
+
 	// sequentially apply needed migrations
 	for dOrder, migration := range needed {
 		// convert all information to a Migration object
@@ -42,6 +69,14 @@ func (e *exec) stageMigrate() error {
 		}
 	}
 
+	// apply changed repeatable migrations after all others
+	for idx, migration := range repeatable {
+		err = e.migrateRepeatable(migration, dID, len(needed)+idx)
+		if err != nil {
+			return err
+		}
+	}
+
 	e.log.Info("migrate successful")
 	return nil
 }
@@ -63,42 +98,56 @@ func genDeploymentID() (string, error) {
 	return fmt.Sprintf("ADAPT-%s-%s-%s-%s", p1, p2, p3, p4), nil
 }
 
-func findNeededMigrations(applied []*Migration, available []*AvailableMigration, log *slog.Logger) []*AvailableMigration {
+func findNeededMigrations(applied []*Migration, available []*AvailableMigration, strictOrdering bool, log *slog.Logger) ([]*AvailableMigration, error) {
 	// if there aren't any applied just return all available
 	if len(applied) == 0 {
-		return available
+		return available, nil
+	}
+
+	appliedIDs := make(map[string]struct{}, len(applied))
+	for _, a := range applied {
+		appliedIDs[a.ID] = struct{}{}
+	}
+
+	// find the position of the last applied migration. Available migrations are
+	// ordered by their dependencies, which can differ from the order of applied
+	// migrations, so we cannot walk both lists side by side.
+	lastApplied := -1
+	for idx, am := range available {
+		if _, ok := appliedIDs[am.ID]; ok {
+			lastApplied = idx
+		}
 	}
 
 	// store all needed migrations
 	needed := make([]*AvailableMigration, 0)
+	var outOfOrder []string
 
-	dbIdx := 0
-	for memIdx := 0; memIdx < len(available); memIdx++ {
-		// migration at current moving index-positions are equal. Therefore this migration
-		// was already applied
-		if applied[dbIdx].ID == available[memIdx].ID {
-			// move db index-position
-			dbIdx++
-
-			// database has new further migrations => add all "remaining" new migrations
-			// into needed and stop
-			if dbIdx == len(applied) {
-				needed = append(needed, available[memIdx+1:]...)
-				break
-			}
-
-			// continue next round (increase available index position and check if there
-			// is one left)
+	for idx, am := range available {
+		if _, ok := appliedIDs[am.ID]; ok {
 			continue
 		}
 
-		// current migration in memory is not applied. This is a "hole" inside our db (most
-		// often caused by merges). Increase memIdx until hole in db is closed.
-		needed = append(needed, available[memIdx])
-		log.Info("found migration hole. Adding local migrations until hole is closed", "migration_id", available[memIdx].ID)
+		// migrations in front of the last applied one are a "hole" inside our db
+		// (most often caused by merges)
+		if idx < lastApplied {
+			if strictOrdering {
+				outOfOrder = append(outOfOrder, am.ID)
+				continue
+			}
+			log.Info("found migration hole. Adding local migrations until hole is closed", "migration_id", am.ID)
+		}
+		needed = append(needed, am)
 	}
 
-	return needed
+	if len(outOfOrder) > 0 {
+		log.Error("found migration hole. Aborting, because strict ordering forbids applying migrations out of order",
+			"migration_ids", outOfOrder, "last_applied_id", available[lastApplied].ID)
+		return nil, fmt.Errorf("adapt: strict ordering forbids applying migrations before the last applied migration %q: %s: %w",
+			available[lastApplied].ID, strings.Join(outOfOrder, ", "), ErrIntegrityProtection)
+	}
+
+	return needed, nil
 }
 
 func convertToMigration(a *AvailableMigration, executor string, deployment string, deploymentOrder int, log *slog.Logger) (*Migration, error) {
@@ -148,7 +197,9 @@ func convertToMigration(a *AvailableMigration, executor string, deployment strin
 func (e *exec) migrate(migration *AvailableMigration, meta *Migration) (err error) {
 	log := e.log.With("migration_id", migration.ID)
 
+	observed := e.observeMigration(migration, meta.Deployment, meta.DeploymentOrder)
 	defer func(started time.Time) {
+		observed(err)
 		if err == nil {
 			log.Debug("migration finished successfully after", "took_duration", time.Since(started))
 		} else {
@@ -176,5 +227,11 @@ func (e *exec) migrate(migration *AvailableMigration, meta *Migration) (err erro
 	}
 
 	// migration finished successful -> add label to store to signal that everything is ok
-	return e.driver.SetMigrationToFinished(migration.ID)
+	err = e.driver.SetMigrationToFinished(migration.ID)
+	if err != nil {
+		return err
+	}
+
+	e.deployed = append(e.deployed, migration.ID)
+	return nil
 }
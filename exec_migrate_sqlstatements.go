@@ -1,6 +1,7 @@
 package adapt
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 )
@@ -26,15 +27,18 @@ func (e *exec) migrateWithSqlStatements(parsed *ParsedMigration, beforeFinishCal
 	}
 
 	exec := func(target DBTarget) error {
-		for _, s := range parsed.Stmts {
+		_, inTx := target.(*sql.Tx)
+		for idx, s := range parsed.Stmts {
 			e.log.Debug("executing statement", "statement", s)
 
 			started := time.Now()
-			if _, err := target.Exec(s); err != nil {
+			_, err := target.Exec(s)
+			end := time.Now()
+			e.notifyStatement(StatementEvent{Statement: s, Index: idx, InTx: inTx, Duration: end.Sub(started), Err: err})
+			if err != nil {
 				e.log.Error("failed executing statement", "statement", s, "error", err)
 				return err
 			}
-			end := time.Now()
 
 			e.log.Debug("executing statement took", "duration", end.Sub(started))
 		}
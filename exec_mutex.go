@@ -1,5 +1,7 @@
 package adapt
 
+import "time"
+
 func (e *exec) acquireDriverLock() error {
 	if e.optDisableDriverLocks {
 		e.log.Debug("locking disabled by option")
@@ -12,14 +14,18 @@ func (e *exec) acquireDriverLock() error {
 	}
 
 	e.log.Debug("locking enabled and supported by driver. Going to acquire an exclusive lock")
+	started := time.Now()
 	err := e.driver.AcquireLock()
 	if err != nil {
 		e.log.Error("failed to acquire driver lock", "error", err)
+		e.notifyLockAcquired(time.Since(started), err)
 		return err
 	}
 
 	e.driverLockAcquired = true
+	e.driverLockSince = time.Now()
 	e.log.Info("acquired an exclusive driver lock")
+	e.notifyLockAcquired(e.driverLockSince.Sub(started), nil)
 
 	return nil
 }
@@ -38,6 +44,7 @@ func (e *exec) releaseDriverLock() error {
 
 	e.driverLockAcquired = false
 	e.log.Info("released driver lock")
+	e.notifyLockReleased(time.Since(e.driverLockSince))
 
 	return nil
 }
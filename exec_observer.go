@@ -0,0 +1,130 @@
+package adapt
+
+import (
+	"time"
+)
+
+// statementObserver is implemented by drivers that execute the statements of
+// a migration themselves (see DatabaseDriverCustomMigration), like the adapter
+// returned by FromSqlStatementsDriver. It allows adapt to report these
+// statements to all Observer.
+type statementObserver interface {
+	observeStatements(fn func(event StatementEvent))
+}
+
+func (e *exec) driverName() string {
+	if e.driver == nil {
+		return ""
+	}
+	return e.driver.Name()
+}
+
+// runStage runs fn and reports it as stage to all Observer. The first stage
+// that fails is remembered in failedStage.
+func (e *exec) runStage(stage Stage, fn func() error) (err error) {
+	defer func() {
+		if err != nil && len(e.failedStage) == 0 {
+			e.failedStage = stage
+		}
+	}()
+
+	if len(e.optObservers) == 0 {
+		return fn()
+	}
+
+	event := StageEvent{
+		Stage:    stage,
+		Executor: e.executor,
+		Driver:   e.driverName(),
+	}
+	for _, o := range e.optObservers {
+		o.OnStageStart(event)
+	}
+
+	started := time.Now()
+	err = fn()
+
+	event.Duration = time.Since(started)
+	event.Err = err
+	for _, o := range e.optObservers {
+		o.OnStageEnd(event)
+	}
+	return err
+}
+
+// observeMigration reports the start of migration to all Observer and returns
+// a function that reports its end
+func (e *exec) observeMigration(migration *AvailableMigration, deployment string, deploymentOrder int) func(err error) {
+	e.currentMigration = migration.ID
+	if len(e.optObservers) == 0 {
+		return func(error) {}
+	}
+
+	event := MigrationEvent{
+		MigrationID:     migration.ID,
+		Executor:        e.executor,
+		Driver:          e.driverName(),
+		Deployment:      deployment,
+		DeploymentOrder: deploymentOrder,
+		Repeatable:      migration.Repeatable,
+	}
+	for _, o := range e.optObservers {
+		o.OnMigrationStart(event)
+	}
+
+	started := time.Now()
+	return func(err error) {
+		event.Duration = time.Since(started)
+		event.Err = err
+		for _, o := range e.optObservers {
+			o.OnMigrationEnd(event)
+		}
+	}
+}
+
+// notifyStatement completes event with the current migration and reports it
+// to all Observer
+func (e *exec) notifyStatement(event StatementEvent) {
+	event.MigrationID = e.currentMigration
+	event.Executor = e.executor
+	event.Driver = e.driverName()
+	for _, o := range e.optObservers {
+		o.OnStatement(event)
+	}
+}
+
+func (e *exec) notifyRollback(migrationID string, duration time.Duration, err error) {
+	event := RollbackEvent{
+		MigrationID: migrationID,
+		Executor:    e.executor,
+		Driver:      e.driverName(),
+		Duration:    duration,
+		Err:         err,
+	}
+	for _, o := range e.optObservers {
+		o.OnRollback(event)
+	}
+}
+
+func (e *exec) notifyLockAcquired(wait time.Duration, err error) {
+	event := LockEvent{
+		Executor: e.executor,
+		Driver:   e.driverName(),
+		Wait:     wait,
+		Err:      err,
+	}
+	for _, o := range e.optObservers {
+		o.OnLockAcquired(event)
+	}
+}
+
+func (e *exec) notifyLockReleased(held time.Duration) {
+	event := LockEvent{
+		Executor: e.executor,
+		Driver:   e.driverName(),
+		Held:     held,
+	}
+	for _, o := range e.optObservers {
+		o.OnLockReleased(event)
+	}
+}
@@ -0,0 +1,42 @@
+package adapt
+
+func (e *exec) stagePlan() (*MigrationPlan, error) {
+	e.log.Debug("plan")
+
+	// resolve the baseline and squashed migrations the same way Migrate does,
+	// without recording them
+	r, err := e.resolveApplied()
+	if err != nil {
+		return nil, err
+	}
+	applied := e.resolvedApplied(r)
+
+	unknown, err := unknownAppliedMigrations(applied, e.known(), !e.optDisableHashIntegrityChecks, e.optHookChangePolicy, e.log)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MigrationPlan{}
+	for idx := len(unknown) - 1; idx >= 0; idx-- {
+		plan.Rollback = append(plan.Rollback, unknown[idx])
+	}
+
+	// unknown migrations are always at the end of applied migrations and get
+	// removed by the rollback
+	remaining := applied[:len(applied)-len(unknown)]
+
+	needed, err := findNeededMigrations(remaining, e.available, e.optStrictOrdering, e.log)
+	if err != nil {
+		return nil, err
+	}
+	err = checkDependencies(remaining, e.available, needed, e.log)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Apply = append(plan.Apply, needed...)
+	plan.Apply = append(plan.Apply, e.changedRepeatable()...)
+
+	e.log.Info("plan successful", "rollback_amount", len(plan.Rollback), "apply_amount", len(plan.Apply))
+	return plan, nil
+}
@@ -0,0 +1,71 @@
+package adapt
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// policyViolation is a statement of a migration matching a PolicyRule
+type policyViolation struct {
+	rule PolicyRule
+	// stmt is the 1-based index of the statement
+	stmt int
+}
+
+// checkPolicy returns all statements of parsed matching one of the rules
+// applicable to dialect. Acknowledged migrations (see
+// ParsedMigration.AllowDestructive) never violate the policy.
+func checkPolicy(parsed *ParsedMigration, dialect Dialect, rules []PolicyRule) []policyViolation {
+	if parsed == nil || parsed.AllowDestructive {
+		return nil
+	}
+
+	var violations []policyViolation
+	for i, stmt := range parsed.Stmts {
+		normalized := strings.ToUpper(normalizeStatement(stmt))
+		for _, rule := range rules {
+			if rule.appliesTo(dialect) && rule.Match(normalized) {
+				violations = append(violations, policyViolation{rule: rule, stmt: i + 1})
+			}
+		}
+	}
+	return violations
+}
+
+// policyTarget is a parsed migration, which is about to be executed
+type policyTarget struct {
+	id     string
+	parsed *ParsedMigration
+}
+
+// upPolicyTargets returns the Up migrations of needed. Hook migrations are
+// executed by Go code, which cannot be checked.
+func upPolicyTargets(needed []*AvailableMigration) []policyTarget {
+	targets := make([]policyTarget, 0, len(needed))
+	for _, am := range needed {
+		targets = append(targets, policyTarget{id: am.ID, parsed: am.ParsedUp})
+	}
+	return targets
+}
+
+// enforcePolicy checks that none of the targets violates the policy before
+// anything is executed.
+func enforcePolicy(targets []policyTarget, dialect Dialect, rules []PolicyRule, log *slog.Logger) error {
+	var offending []string
+	for _, target := range targets {
+		violations := checkPolicy(target.parsed, dialect, rules)
+		for _, v := range violations {
+			log.Error("migration statement violates policy. Acknowledge it using \"-- +adapt AllowDestructive\" if intended",
+				"migration_id", target.id, "statement", v.stmt, "rule", v.rule.Name, "reason", v.rule.Reason)
+		}
+		if len(violations) > 0 {
+			offending = append(offending, target.id)
+		}
+	}
+
+	if len(offending) > 0 {
+		return fmt.Errorf("adapt: migrations violate the policy: %s: %w", strings.Join(offending, ", "), ErrPolicyViolation)
+	}
+	return nil
+}
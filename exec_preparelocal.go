@@ -3,6 +3,7 @@ package adapt
 import (
 	"fmt"
 	"log/slog"
+	"slices"
 	"sort"
 )
 
@@ -10,26 +11,51 @@ func (e *exec) stagePrepareLocal() error {
 	e.log.Debug("prepare local")
 
 	// merge all sources into available migrations
-	available, err := mergeSources(e.sources, e.log)
+	available, skipped, err := mergeSources(e.sources, e.optOrder, e.optTags, e.log)
 	if err != nil {
 		return err
 	}
 
+	// separate repeatable migrations, as they are applied after all others
+	available, e.repeatable = splitRepeatable(available)
+
+	// validate migration ids against the configured naming scheme
+	err = validateIDs(available, e.optIDValidators, e.log)
+	if err != nil {
+		return err
+	}
+
+	// order migrations so that dependencies are applied first
+	available, err = orderByDependencies(available, e.log)
+	if err != nil {
+		return err
+	}
+
+	// switch to normalized hashes when requested
+	if e.optNormalizedHashes {
+		for _, am := range available {
+			if am.ParsedUp != nil {
+				am.Hash = am.ParsedUp.NormalizedHash()
+			}
+		}
+	}
+
 	// save to exec
 	e.available = available
+	e.skipped = skipped
 
 	e.log.Info("prepare local successful")
 	return nil
 }
 
-func mergeSources(sources SourceCollection, log *slog.Logger) ([]*AvailableMigration, error) {
+func mergeSources(sources SourceCollection, cmp Comparator, tags tagFilter, log *slog.Logger) (available []*AvailableMigration, skipped []*AvailableMigration, err error) {
 	migrationMap := make(map[string]*AvailableMigration)
 
 	for _, src := range sources {
 		migrations, err := src.ListMigrations()
 		if err != nil {
 			log.Error("listing migrations failed", "error", err)
-			return nil, err
+			return nil, nil, err
 		}
 
 		for _, id := range migrations {
@@ -37,7 +63,7 @@ func mergeSources(sources SourceCollection, log *slog.Logger) ([]*AvailableMigra
 			// sources!
 			if _, ok := migrationMap[id]; ok {
 				log.Error("migration was provided by multiple sources", "migration_id", id)
-				return nil, fmt.Errorf("adapt: migration was provided by multiple sources")
+				return nil, nil, fmt.Errorf("adapt: migration was provided by multiple sources")
 			}
 
 			// migration with this id isn't available -> add it
@@ -47,24 +73,79 @@ func mergeSources(sources SourceCollection, log *slog.Logger) ([]*AvailableMigra
 			}
 			err = am.Enrich(log)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			migrationMap[id] = am
 		}
 	}
 
-	// copy all migrations from map to slice
-	migrationList := make([]*AvailableMigration, 0)
+	// copy all migrations from map to slice, while separating migrations
+	// filtered by their tags
+	available = make([]*AvailableMigration, 0)
 	for _, m := range migrationMap {
-		migrationList = append(migrationList, m)
+		if tags.matches(m.Tags) {
+			available = append(available, m)
+		} else {
+			skipped = append(skipped, m)
+		}
 	}
 
 	// sort the ordering of our migrations
-	sort.Slice(migrationList, func(i, j int) bool {
-		return migrationList[i].ID < migrationList[j].ID
+	sortAvailable(available, cmp)
+	sortAvailable(skipped, cmp)
+
+	log.Info("merged all sources into a single migration collection", "sources_amount", len(sources), "migrations_amount", len(available), "skipped_amount", len(skipped))
+	return available, skipped, nil
+}
+
+func sortAvailable(available []*AvailableMigration, cmp Comparator) {
+	sort.Slice(available, func(i, j int) bool {
+		return cmp(available[i].ID, available[j].ID) < 0
 	})
+}
+
+// tagFilter selects migrations by their tags. See IncludeTags and ExcludeTags.
+type tagFilter struct {
+	include []string
+	exclude []string
+}
 
-	log.Info("merged all sources into a single migration collection", "sources_amount", len(sources), "migrations_amount", len(migrationList))
-	return migrationList, nil
+func (f tagFilter) matches(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if slices.Contains(f.exclude, tag) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if slices.Contains(f.include, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateIDs(available []*AvailableMigration, validators []IDValidator, log *slog.Logger) error {
+	var invalid int
+	for _, am := range available {
+		for _, validate := range validators {
+			if err := validate(am.ID); err != nil {
+				log.Error("migration id doesn't match the configured naming scheme", "migration_id", am.ID, "error", err)
+				invalid++
+				break
+			}
+		}
+	}
+
+	if invalid > 0 {
+		log.Error("found migration ids violating the configured naming scheme", "invalid_amount", invalid)
+		return ErrInvalidSource
+	}
+	return nil
 }
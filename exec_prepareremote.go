@@ -3,6 +3,7 @@ package adapt
 import (
 	"fmt"
 	"log/slog"
+	"sort"
 )
 
 func (e *exec) stagePrepareRemote() error {
@@ -22,6 +23,13 @@ func (e *exec) stagePrepareRemote() error {
 		return err
 	}
 
+	// separate runs of repeatable migrations
+	applied, e.appliedRepeatable = e.splitAppliedRepeatable(applied)
+
+	// order applied migrations like available ones, as drivers report them in
+	// lexical order
+	sortApplied(applied, e.optOrder)
+
 	// save to exec
 	e.applied = applied
 
@@ -40,3 +48,9 @@ func healthCheckAppliedMigration(applied []*Migration, log *slog.Logger) error {
 
 	return nil
 }
+
+func sortApplied(applied []*Migration, cmp Comparator) {
+	sort.SliceStable(applied, func(i, j int) bool {
+		return cmp(applied[i].ID, applied[j].ID) < 0
+	})
+}
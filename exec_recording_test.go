@@ -0,0 +1,551 @@
+// The tests in this file use adapttest.RecordingDriver to assert the exact
+// statements and transaction operations performed by adapt. As adapttest
+// imports adapt they must live in the external test package.
+package adapt_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harwoeck/adapt"
+	"github.com/harwoeck/adapt/adapttest"
+	"github.com/harwoeck/adapt/adapttest/fakesql"
+)
+
+func eventStrings(events []fakesql.Event) []string {
+	s := make([]string, 0, len(events))
+	for _, e := range events {
+		s = append(s, e.String())
+	}
+	return s
+}
+
+func TestMigrate_RecordingDriver(t *testing.T) {
+	errInjected := errors.New("injected")
+
+	tests := []struct {
+		name         string
+		up           string
+		opts         []adapttest.RecordingOption
+		failAt       int
+		wantEvents   []string
+		wantErr      bool
+		wantFinished bool
+	}{
+		{
+			name: "transaction",
+			up:   "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			wantEvents: []string{
+				"begin",
+				"exec: CREATE TABLE a (id INT);",
+				"exec: CREATE TABLE b (id INT);",
+				"commit",
+			},
+			wantFinished: true,
+		},
+		{
+			name: "no transaction option",
+			up:   "-- +adapt NoTransaction\nCREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			wantEvents: []string{
+				"exec: CREATE TABLE a (id INT);",
+				"exec: CREATE TABLE b (id INT);",
+			},
+			wantFinished: true,
+		},
+		{
+			name: "driver without transactions",
+			up:   "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			opts: []adapttest.RecordingOption{adapttest.RecordingDisableTx()},
+			wantEvents: []string{
+				"exec: CREATE TABLE a (id INT);",
+				"exec: CREATE TABLE b (id INT);",
+			},
+			wantFinished: true,
+		},
+		{
+			name:   "failing statement is rolled back",
+			up:     "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\nCREATE TABLE c (id INT);",
+			failAt: 2,
+			wantEvents: []string{
+				"begin",
+				"exec: CREATE TABLE a (id INT);",
+				"exec: CREATE TABLE b (id INT);",
+				"rollback",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "failing statement without transaction",
+			up:     "-- +adapt NoTransaction\nCREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\nCREATE TABLE c (id INT);",
+			failAt: 2,
+			wantEvents: []string{
+				"exec: CREATE TABLE a (id INT);",
+				"exec: CREATE TABLE b (id INT);",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := adapttest.NewRecordingDriver(tt.opts...)
+			if tt.failAt > 0 {
+				driver.Recorder().FailStatement(tt.failAt, errInjected)
+			}
+
+			err := adapt.Migrate("test", driver, adapt.SourceCollection{
+				adapt.NewMemoryFSSource(map[string]string{"1_a.up.sql": tt.up}),
+			}, adapt.DisableLogger())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := eventStrings(driver.Recorder().Events()); !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("Migrate() events = %q, want %q", got, tt.wantEvents)
+			}
+
+			migrations := driver.Migrations()
+			if len(migrations) != 1 {
+				t.Fatalf("Migrate() stored %d migrations, want 1", len(migrations))
+			}
+			if finished := migrations[0].Finished != nil; finished != tt.wantFinished {
+				t.Errorf("Migrate() finished = %v, want %v", finished, tt.wantFinished)
+			}
+		})
+	}
+}
+
+func TestRollback_RecordingDriver(t *testing.T) {
+	driver := adapttest.NewRecordingDriver()
+	sources := adapt.SourceCollection{
+		adapt.NewMemoryFSSource(map[string]string{
+			"1_a.sql": "-- +adapt Up\nCREATE TABLE a (id INT);\n-- +adapt Down\nDROP TABLE a;",
+		}),
+	}
+
+	err := adapt.Migrate("test", driver, sources, adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	driver.Recorder().Reset()
+
+	_, err = adapt.Rollback(driver, sources, 1, adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	// the stored migration is deleted within the transaction of the down
+	// migration
+	want := []string{
+		"begin",
+		"exec: DROP TABLE a;",
+		"exec: DELETE FROM _adapt_migrations WHERE id=?",
+		"commit",
+	}
+	if got := eventStrings(driver.Recorder().Events()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rollback() events = %q, want %q", got, want)
+	}
+	if got := driver.Migrations(); len(got) != 0 {
+		t.Errorf("Rollback() left %d stored migrations, want 0", len(got))
+	}
+}
+
+func TestRollback_EnforcePolicy(t *testing.T) {
+	driver := adapttest.NewRecordingDriver()
+	executed := false
+	sql := adapt.NewMemoryFSSource(map[string]string{
+		"1_a.sql": "-- +adapt Up\nCREATE TABLE a (id INT);\n-- +adapt Down\nDROP TABLE a;",
+	})
+	hook := adapt.NewCodeSource("2_hook", adapt.Hook{
+		MigrateUp: func() error {
+			executed = true
+			return nil
+		},
+		MigrateDown: func() *adapt.ParsedMigration {
+			return &adapt.ParsedMigration{UseTx: true, Stmts: []string{"DROP TABLE b;"}}
+		},
+	})
+	policy := adapt.EnforcePolicy(adapt.DefaultPolicyRules()...)
+
+	// Go code of hooks cannot be checked and is never blocked
+	err := adapt.Migrate("test", driver, adapt.SourceCollection{sql, hook}, policy, adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if !executed {
+		t.Fatalf("Migrate() didn't execute hook")
+	}
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantIDs string
+	}{
+		{"rollback", func() error {
+			_, err := adapt.Rollback(driver, adapt.SourceCollection{sql, hook}, 2, policy, adapt.DisableLogger())
+			return err
+		}, "2_hook, 1_a"},
+		{"automatic rollback of unknown hook", func() error {
+			return adapt.Migrate("test", driver, adapt.SourceCollection{sql}, policy, adapt.DisableLogger())
+		}, "2_hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver.Recorder().Reset()
+
+			err := tt.run()
+			if !errors.Is(err, adapt.ErrPolicyViolation) {
+				t.Fatalf("error = %v, want %v", err, adapt.ErrPolicyViolation)
+			}
+			if !strings.Contains(err.Error(), tt.wantIDs) {
+				t.Errorf("error = %v, want offending ids %q listed", err, tt.wantIDs)
+			}
+			for _, event := range eventStrings(driver.Recorder().Events()) {
+				if strings.HasPrefix(event, "exec: DROP") {
+					t.Errorf("down migration executed despite policy violation: %q", event)
+				}
+			}
+			if got := driver.Migrations(); len(got) != 2 {
+				t.Errorf("%d stored migrations, want 2", len(got))
+			}
+		})
+	}
+}
+
+// schemaRecordingDriver reports the tables created and dropped through the
+// RecordingDriver as its schema
+type schemaRecordingDriver struct {
+	*adapttest.RecordingDriver
+	tables map[string]bool
+}
+
+func newSchemaRecordingDriver() *schemaRecordingDriver {
+	d := &schemaRecordingDriver{RecordingDriver: adapttest.NewRecordingDriver(), tables: make(map[string]bool)}
+	d.Recorder().OnExec(func(query string, args []driver.Value) error {
+		fields := strings.Fields(strings.TrimSuffix(query, ";"))
+		if len(fields) >= 3 && fields[1] == "TABLE" {
+			switch fields[0] {
+			case "CREATE":
+				d.tables[fields[2]] = true
+			case "DROP":
+				delete(d.tables, fields[2])
+			}
+		}
+		return nil
+	})
+	return d
+}
+
+func (d *schemaRecordingDriver) InspectSchema() (*adapt.SchemaSnapshot, error) {
+	var objects []string
+	for name := range d.tables {
+		objects = append(objects, "table "+name)
+	}
+	return adapt.NewSchemaSnapshot(objects), nil
+}
+
+func TestRecordSchemaSnapshot_Rollback(t *testing.T) {
+	d := newSchemaRecordingDriver()
+	a := adapt.NewMemoryFSSource(map[string]string{
+		"1_a.sql": "-- +adapt Up\nCREATE TABLE a (id INT);\n-- +adapt Down\nDROP TABLE a;",
+	})
+	b := adapt.NewMemoryFSSource(map[string]string{
+		"2_b.sql": "-- +adapt Up\nCREATE TABLE b (id INT);\n-- +adapt Down\nDROP TABLE b;",
+	})
+
+	checkDrift := func(wantID string, wantReason string) {
+		t.Helper()
+		report, err := adapt.CheckDrift(d, adapt.DisableLogger())
+		if err != nil {
+			t.Fatalf("CheckDrift() error = %v", err)
+		}
+		if report.Drifted || report.MigrationID != wantID {
+			t.Errorf("CheckDrift() = %+v, want no drift after %s", report, wantID)
+		}
+		entries, err := d.ListJournalEntries()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := entries[len(entries)-1].Reason; got != wantReason {
+			t.Errorf("snapshot reason = %q, want %q", got, wantReason)
+		}
+	}
+	migrate := func(sources ...adapt.Source) {
+		t.Helper()
+		err := adapt.Migrate("test", d, sources, adapt.RecordSchemaSnapshot(), adapt.DisableLogger())
+		if err != nil {
+			t.Fatalf("Migrate() error = %v", err)
+		}
+	}
+
+	migrate(a, b)
+	checkDrift("2_b", "post-migration schema snapshot")
+
+	_, err := adapt.Rollback(d, adapt.SourceCollection{a, b}, 1, adapt.RecordSchemaSnapshot(), adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	checkDrift("1_a", "post-rollback schema snapshot")
+
+	// automatic rollback of a migration that isn't available anymore
+	migrate(a, b)
+	migrate(a)
+	checkDrift("1_a", "post-rollback schema snapshot")
+}
+
+// customMigrationDriver executes statements on its own, without a transaction
+type customMigrationDriver struct {
+	*adapttest.RecordingDriver
+	calls int
+}
+
+func (d *customMigrationDriver) Migrate(migration *adapt.ParsedMigration, beforeFinish func(target adapt.DBTarget) error) error {
+	d.calls++
+	for _, stmt := range migration.Stmts {
+		if _, err := d.DB().Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if beforeFinish != nil {
+		return beforeFinish(d.DB())
+	}
+	return nil
+}
+
+func TestMigrate_DatabaseDriverCustomMigration(t *testing.T) {
+	driver := &customMigrationDriver{RecordingDriver: adapttest.NewRecordingDriver()}
+
+	err := adapt.Migrate("test", driver, adapt.SourceCollection{
+		adapt.NewMemoryFSSource(map[string]string{
+			"1_a.up.sql": "CREATE TABLE a (id INT);",
+			"2_b.up.sql": "CREATE TABLE b (id INT);",
+		}),
+	}, adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	if driver.calls != 2 {
+		t.Errorf("Migrate() called custom migration %d times, want 2", driver.calls)
+	}
+	want := []string{
+		"exec: CREATE TABLE a (id INT);",
+		"exec: CREATE TABLE b (id INT);",
+	}
+	if got := eventStrings(driver.Recorder().Events()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Migrate() events = %q, want %q", got, want)
+	}
+}
+
+// traceMetrics records all calls to adapt.Metrics as short strings
+type traceMetrics struct {
+	trace []string
+}
+
+func (m *traceMetrics) ObserveMigration(labels adapt.MetricLabels, outcome adapt.Outcome, _ time.Duration) {
+	m.trace = append(m.trace, fmt.Sprintf("migration %s %s %s", labels.Driver, labels.Executor, outcome))
+}
+
+func (m *traceMetrics) ObserveStatement(_ adapt.MetricLabels, outcome adapt.Outcome, _ time.Duration) {
+	m.trace = append(m.trace, fmt.Sprintf("statement %s", outcome))
+}
+
+func (m *traceMetrics) ObserveLockWait(adapt.MetricLabels, time.Duration) {
+	m.trace = append(m.trace, "lock wait")
+}
+
+func (m *traceMetrics) IncFailures(_ adapt.MetricLabels, stage adapt.Stage) {
+	m.trace = append(m.trace, fmt.Sprintf("failure %s", stage))
+}
+
+func (m *traceMetrics) SetPendingMigrations(driver string, pending int) {
+	m.trace = append(m.trace, fmt.Sprintf("pending %s %d", driver, pending))
+}
+
+func TestCollectMetrics(t *testing.T) {
+	tests := []struct {
+		name      string
+		failAt    int
+		lockErr   error
+		wantTrace []string
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			wantTrace: []string{
+				"lock wait",
+				"pending driver_recording 2",
+				"statement success",
+				"statement success",
+				"migration driver_recording test success",
+				"statement success",
+				"migration driver_recording test success",
+				"pending driver_recording 0",
+			},
+		},
+		{
+			name:   "failure",
+			failAt: 2,
+			wantTrace: []string{
+				"lock wait",
+				"pending driver_recording 2",
+				"statement success",
+				"statement failure",
+				"migration driver_recording test failure",
+				"pending driver_recording 2",
+				"failure migrate",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "lock failure",
+			lockErr: errors.New("locked"),
+			wantTrace: []string{
+				"lock wait",
+				"failure lock",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := &lockingDriver{RecordingDriver: adapttest.NewRecordingDriver(), lockErr: tt.lockErr}
+			if tt.failAt > 0 {
+				driver.Recorder().FailStatement(tt.failAt, errors.New("injected"))
+			}
+
+			m := &traceMetrics{}
+			err := adapt.Migrate("test", driver, adapt.SourceCollection{
+				adapt.NewMemoryFSSource(map[string]string{
+					"1_a.up.sql": "CREATE TABLE a (id INT);\nCREATE INDEX a_id ON a (id);",
+					"2_b.up.sql": "CREATE TABLE b (id INT);",
+				}),
+			}, adapt.DisableLogger(), adapt.CollectMetrics(m))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(m.trace, tt.wantTrace) {
+				t.Errorf("Migrate() metrics = %q, want %q", m.trace, tt.wantTrace)
+			}
+		})
+	}
+}
+
+func TestMigrate_RequireSignedManifest(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approvedUp := "CREATE TABLE a (id INT);"
+	approvedDown := "DROP TABLE a;"
+	approved := "-- +adapt Up\n" + approvedUp + "\n-- +adapt Down\n" + approvedDown
+	hashOf := func(content string) string {
+		parsed, err := adapt.Parse(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *parsed.Hash()
+	}
+	manifest, _ := json.Marshal(&adapt.Manifest{Migrations: []*adapt.ManifestEntry{
+		{ID: "20240101_1200_init", Hash: hashOf(approvedUp), DownHash: hashOf(approvedDown)},
+	}})
+	signature := adapt.SignManifest(priv, manifest)
+
+	tests := []struct {
+		name        string
+		src         adapt.SourceCollection
+		signature   []byte
+		wantErr     error
+		wantApplied []string
+	}{
+		{"approved", adapt.SourceCollection{adapt.NewMemoryFSSource(map[string]string{
+			"20240101_1200_init.sql": approved,
+		})}, signature, nil, []string{"20240101_1200_init"}},
+		{"changed content", adapt.SourceCollection{adapt.NewMemoryFSSource(map[string]string{
+			"20240101_1200_init.sql": "-- +adapt Up\nDROP TABLE users;\n-- +adapt Down\n" + approvedDown,
+		})}, signature, adapt.ErrIntegrityProtection, nil},
+		{"changed down", adapt.SourceCollection{adapt.NewMemoryFSSource(map[string]string{
+			"20240101_1200_init.sql": "-- +adapt Up\n" + approvedUp + "\n-- +adapt Down\nDROP TABLE users;",
+		})}, signature, adapt.ErrIntegrityProtection, nil},
+		{"missing down", adapt.SourceCollection{adapt.NewMemoryFSSource(map[string]string{
+			"20240101_1200_init.up.sql": approvedUp,
+		})}, signature, adapt.ErrIntegrityProtection, nil},
+		{"not in manifest", adapt.SourceCollection{adapt.NewMemoryFSSource(map[string]string{
+			"20240101_1200_init.sql":     approved,
+			"20240102_1200_extra.up.sql": "CREATE TABLE b (id INT);",
+		})}, signature, adapt.ErrIntegrityProtection, nil},
+		{"hook without hash", adapt.SourceCollection{adapt.NewCodeSource("20240101_1200_init", adapt.Hook{
+			MigrateUp: func() error { return nil },
+		})}, signature, adapt.ErrIntegrityProtection, nil},
+		{"invalid signature", adapt.SourceCollection{adapt.NewMemoryFSSource(map[string]string{
+			"20240101_1200_init.sql": approved,
+		})}, adapt.SignManifest(priv, []byte("{}")), adapt.ErrInvalidSignature, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := adapttest.NewRecordingDriver()
+
+			err := adapt.Migrate("test", driver, tt.src, adapt.DisableLogger(),
+				adapt.RequireSignedManifest(pub, manifest, tt.signature))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+
+			var applied []string
+			for _, m := range driver.Migrations() {
+				applied = append(applied, m.ID)
+			}
+			if !reflect.DeepEqual(applied, tt.wantApplied) {
+				t.Errorf("Migrate() applied = %v, want %v", applied, tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestMigrate_RepeatableRefreshesStoredMigration(t *testing.T) {
+	driver := adapttest.NewRecordingDriver()
+	source := func(view string, down string) adapt.SourceCollection {
+		return adapt.SourceCollection{adapt.NewMemoryFSSource(map[string]string{
+			"R__views.sql": "-- +adapt Up\nCREATE OR REPLACE VIEW v AS " + view + ";\n-- +adapt Down\n" + down + ";",
+		})}
+	}
+
+	err := adapt.Migrate("deployer@v1", driver, source("SELECT 1", "DROP VIEW v"), adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Migrate() first run error = %v", err)
+	}
+	err = adapt.Migrate("deployer@v2", driver, source("SELECT 2", "DROP VIEW IF EXISTS v"), adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Migrate() second run error = %v", err)
+	}
+
+	migrations := driver.Migrations()
+	if len(migrations) != 1 {
+		t.Fatalf("Migrate() stored %d migrations, want 1", len(migrations))
+	}
+	m := migrations[0]
+	if m.Executor != "deployer@v2" {
+		t.Errorf("stored Executor = %q, want %q", m.Executor, "deployer@v2")
+	}
+	if m.Down == nil || !strings.Contains(string(*m.Down), "DROP VIEW IF EXISTS v;") {
+		t.Errorf("stored Down wasn't refreshed")
+	}
+	if m.Finished == nil {
+		t.Errorf("stored migration isn't finished")
+	}
+}
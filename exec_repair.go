@@ -0,0 +1,116 @@
+package adapt
+
+import (
+	"fmt"
+	"time"
+)
+
+func (e *exec) runRepair(cfg *repairConfig) (entries []*JournalEntry, err error) {
+	err = e.runLocked(func() error {
+		return e.runStage(StageRepair, func() error {
+			var repairErr error
+			entries, repairErr = e.stageRepair(cfg)
+			return repairErr
+		})
+	})
+	return entries, err
+}
+
+func (e *exec) stageRepair(cfg *repairConfig) ([]*JournalEntry, error) {
+	e.log.Debug("repair")
+
+	journal, ok := asJournal(e.driver)
+	if !ok {
+		e.log.Error("driver doesn't implement DriverJournal. Repairs cannot be recorded")
+		return nil, fmt.Errorf("adapt: driver doesn't support repairs")
+	}
+
+	applied, err := e.driver.ListMigrations()
+	if err != nil {
+		e.log.Error("failed to list already applied migrations from driver", "error", err)
+		return nil, err
+	}
+
+	var entries []*JournalEntry
+	record := func(kind string, migrationID string, detail string) error {
+		entry := &JournalEntry{
+			Kind:        kind,
+			MigrationID: migrationID,
+			Executor:    e.executor,
+			Created:     time.Now().UTC(),
+			Reason:      cfg.reason,
+			Detail:      detail,
+		}
+		if err := journal.AddJournalEntry(entry); err != nil {
+			e.log.Error("failed to record repair action", "migration_id", migrationID, "kind", kind, "error", err)
+			return err
+		}
+
+		e.log.Info("repaired migration", "migration_id", migrationID, "kind", kind, "detail", detail)
+		entries = append(entries, entry)
+		return nil
+	}
+
+	for _, a := range applied {
+		if cfg.only != nil {
+			if _, ok := cfg.only[a.ID]; !ok {
+				continue
+			}
+		}
+
+		if a.Finished == nil {
+			switch {
+			case cfg.markFinished:
+				if err = e.driver.SetMigrationToFinished(a.ID); err != nil {
+					e.log.Error("failed to mark migration as finished", "migration_id", a.ID, "error", err)
+					return entries, err
+				}
+				if err = record(JournalRepairFinished, a.ID, fmt.Sprintf("started %s", a.Started.Format(time.RFC3339Nano))); err != nil {
+					return entries, err
+				}
+			case cfg.deleteUnfinished:
+				remover, ok := e.driver.(DriverMigrationRemover)
+				if !ok {
+					e.log.Error("driver doesn't implement DriverMigrationRemover. Unfinished migrations cannot be deleted")
+					return entries, fmt.Errorf("adapt: driver doesn't support deleting migrations")
+				}
+				if err = remover.RemoveMigration(a.ID); err != nil {
+					e.log.Error("failed to delete unfinished migration", "migration_id", a.ID, "error", err)
+					return entries, err
+				}
+				if err = record(JournalRepairDeleted, a.ID, fmt.Sprintf("started %s", a.Started.Format(time.RFC3339Nano))); err != nil {
+					return entries, err
+				}
+				continue
+			}
+		}
+
+		if cfg.hashes {
+			local := e.findAvailable(a.ID)
+			if local == nil || local.Hash == nil || (a.Hash != nil && hashEqual(a.Hash, local)) {
+				continue
+			}
+
+			updater, ok := asHashUpdater(e.driver)
+			if !ok {
+				e.log.Error("driver doesn't implement DriverHashUpdater. Hashes cannot be repaired")
+				return entries, fmt.Errorf("adapt: driver doesn't support updating hashes")
+			}
+			if err = updater.UpdateMigrationHash(a.ID, local.Hash); err != nil {
+				e.log.Error("failed to update stored hash", "migration_id", a.ID, "error", err)
+				return entries, err
+			}
+
+			oldHash := "<nil>"
+			if a.Hash != nil {
+				oldHash = *a.Hash
+			}
+			if err = record(JournalRepairHash, a.ID, fmt.Sprintf("%s -> %s", oldHash, *local.Hash)); err != nil {
+				return entries, err
+			}
+		}
+	}
+
+	e.log.Info("repair successful", "actions_amount", len(entries))
+	return entries, nil
+}
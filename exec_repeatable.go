@@ -0,0 +1,210 @@
+package adapt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// splitRepeatable separates repeatable migrations from all others, while
+// keeping the order of both.
+func splitRepeatable(available []*AvailableMigration) (versioned []*AvailableMigration, repeatable []*AvailableMigration) {
+	versioned = make([]*AvailableMigration, 0, len(available))
+	for _, am := range available {
+		if am.Repeatable {
+			repeatable = append(repeatable, am)
+		} else {
+			versioned = append(versioned, am)
+		}
+	}
+	return versioned, repeatable
+}
+
+// splitAppliedRepeatable separates the stored runs of repeatable migrations
+// from all other applied migrations. A stored migration belongs to a
+// repeatable migration when its ID starts with RepeatablePrefix or a local
+// repeatable migration (including ones filtered by their tags) uses its ID.
+func (e *exec) splitAppliedRepeatable(applied []*Migration) (versioned []*Migration, repeatable []*Migration) {
+	ids := make(map[string]struct{}, len(e.repeatable))
+	for _, am := range e.repeatable {
+		ids[am.ID] = struct{}{}
+	}
+	for _, am := range e.skipped {
+		if am.Repeatable {
+			ids[am.ID] = struct{}{}
+		}
+	}
+
+	versioned = make([]*Migration, 0, len(applied))
+	for _, a := range applied {
+		_, isRepeatable := ids[a.ID]
+		if isRepeatable || strings.HasPrefix(a.ID, RepeatablePrefix) {
+			repeatable = append(repeatable, a)
+		} else {
+			versioned = append(versioned, a)
+		}
+	}
+	return versioned, repeatable
+}
+
+func (e *exec) findAppliedRepeatable(id string) *Migration {
+	for _, a := range e.appliedRepeatable {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// changedRepeatable returns all repeatable migrations that were never applied
+// or whose Hash changed since their last run. Repeatable migrations without a
+// Hash are only applied once.
+func (e *exec) changedRepeatable() []*AvailableMigration {
+	var changed []*AvailableMigration
+	for _, am := range e.repeatable {
+		stored := e.findAppliedRepeatable(am.ID)
+		if stored != nil && (am.Hash == nil || (stored.Hash != nil && hashEqual(stored.Hash, am))) {
+			continue
+		}
+		changed = append(changed, am)
+	}
+	return changed
+}
+
+func (e *exec) migrateRepeatable(migration *AvailableMigration, deployment string, deploymentOrder int) error {
+	stored := e.findAppliedRepeatable(migration.ID)
+
+	// the first run is stored like every other migration
+	if stored == nil {
+		meta, err := convertToMigration(migration, e.executor, deployment, deploymentOrder, e.log)
+		if err != nil {
+			return err
+		}
+		err = e.migrate(migration, meta)
+		if err != nil {
+			return err
+		}
+		e.appliedRepeatable = append(e.appliedRepeatable, meta)
+		return e.recordRepeatableRun(migration, "first run", "<nil>")
+	}
+
+	_, canRemove := e.driver.(DriverMigrationRemover)
+	_, canUpdate := asHashUpdater(e.driver)
+	if !canRemove && !canUpdate {
+		e.log.Error("driver implements neither DriverMigrationRemover nor DriverHashUpdater. Repeatable migrations cannot be applied again", "migration_id", migration.ID)
+		return fmt.Errorf("adapt: driver doesn't support repeatable migrations")
+	}
+
+	e.log.Info("applying changed repeatable migration", "migration_id", migration.ID, "deployment", deployment, "deployment_order", deploymentOrder)
+
+	oldHash := hashString(stored.Hash)
+	meta, err := e.rerunRepeatable(migration, deployment, deploymentOrder)
+	if err != nil {
+		return err
+	}
+
+	if meta != nil {
+		*stored = *meta
+	} else {
+		stored.Hash = migration.Hash
+	}
+	return e.recordRepeatableRun(migration, "content changed", oldHash)
+}
+
+// rerunRepeatable applies a changed repeatable migration again. Afterwards the
+// stored migration is replaced with a new one, so that its Down migration and
+// Executor are refreshed as well, and returned. Drivers that cannot remove
+// migrations only get their stored hash updated and nil is returned.
+func (e *exec) rerunRepeatable(migration *AvailableMigration, deployment string, deploymentOrder int) (meta *Migration, err error) {
+	meta, err = convertToMigration(migration, e.executor, deployment, deploymentOrder, e.log)
+	if err != nil {
+		return nil, err
+	}
+
+	observed := e.observeMigration(migration, deployment, deploymentOrder)
+	defer func() {
+		observed(err)
+	}()
+
+	switch src := migration.Source.(type) {
+	case SqlStatementsSource:
+		err = e.migrateWithSqlStatements(migration.ParsedUp, nil)
+	case HookSource:
+		err = e.migrateWithHook(migration.ID, src)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if remover, ok := e.driver.(DriverMigrationRemover); ok {
+		err = e.replaceStoredMigration(remover, meta)
+	} else {
+		e.log.Warn("driver doesn't implement DriverMigrationRemover. Only the hash of the repeatable migration is updated, while its down migration and executor remain from the first run",
+			"migration_id", migration.ID)
+		meta = nil
+		err = e.updateStoredHash(migration)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e.deployed = append(e.deployed, migration.ID)
+	return meta, nil
+}
+
+func (e *exec) replaceStoredMigration(remover DriverMigrationRemover, meta *Migration) error {
+	err := remover.RemoveMigration(meta.ID)
+	if err != nil {
+		e.log.Error("failed to remove stored repeatable migration", "migration_id", meta.ID, "error", err)
+		return err
+	}
+	err = e.driver.AddMigration(meta)
+	if err != nil {
+		e.log.Error("failed to store repeatable migration", "migration_id", meta.ID, "error", err)
+		return err
+	}
+	err = e.driver.SetMigrationToFinished(meta.ID)
+	if err != nil {
+		return err
+	}
+
+	finished := time.Now().UTC()
+	meta.Finished = &finished
+	return nil
+}
+
+func (e *exec) updateStoredHash(migration *AvailableMigration) error {
+	updater, _ := asHashUpdater(e.driver)
+	err := updater.UpdateMigrationHash(migration.ID, migration.Hash)
+	if err != nil {
+		e.log.Error("failed to update stored hash of repeatable migration", "migration_id", migration.ID, "error", err)
+		return err
+	}
+	return e.driver.SetMigrationToFinished(migration.ID)
+}
+
+func (e *exec) recordRepeatableRun(migration *AvailableMigration, reason string, oldHash string) error {
+	journal, ok := asJournal(e.driver)
+	if !ok {
+		e.log.Debug("driver doesn't implement DriverJournal. Run of repeatable migration isn't recorded", "migration_id", migration.ID)
+		return nil
+	}
+
+	newHash := "<nil>"
+	if migration.Hash != nil {
+		newHash = *migration.Hash
+	}
+	err := journal.AddJournalEntry(&JournalEntry{
+		Kind:        JournalRepeatableRun,
+		MigrationID: migration.ID,
+		Executor:    e.executor,
+		Created:     time.Now().UTC(),
+		Reason:      reason,
+		Detail:      fmt.Sprintf("%s -> %s", oldHash, newHash),
+	})
+	if err != nil {
+		e.log.Error("failed to record run of repeatable migration", "migration_id", migration.ID, "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package adapt
+
+// appliedResolution describes the migrations Migrate records without executing
+// them, before it compares the applied against the available migrations.
+type appliedResolution struct {
+	// baseline contains the migrations recorded by BaselineOnEmptyMeta
+	baseline []*AvailableMigration
+	// squashed contains the squashed migrations whose replaced migrations are
+	// applied
+	squashed []*AvailableMigration
+	// replaced contains the applied migrations replaced by a squashed
+	// migration, that aren't available anymore
+	replaced []*Migration
+}
+
+// resolveApplied computes the appliedResolution of the current applied
+// migrations without changing anything, so that Plan and Status report the
+// same outcome as Migrate.
+//
+// Replaces is ordered and flattened by Squash, so the last replaced migration
+// is applied by every database that applied the complete range, no matter
+// whether it applied the original migrations or an earlier squashed migration
+// in their place.
+func (e *exec) resolveApplied() (*appliedResolution, error) {
+	r := &appliedResolution{}
+
+	// baseline empty meta-storage if requested
+	if len(e.applied) == 0 && len(e.optBaselineOnEmptyMeta) > 0 {
+		var err error
+		r.baseline, err = e.baselineMigrations(e.optBaselineOnEmptyMeta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	appliedIDs := make(map[string]struct{}, len(e.applied)+len(r.baseline))
+	for _, a := range e.applied {
+		appliedIDs[a.ID] = struct{}{}
+	}
+	for _, am := range r.baseline {
+		appliedIDs[am.ID] = struct{}{}
+	}
+	availableIDs := make(map[string]struct{}, len(e.available))
+	for _, am := range e.available {
+		availableIDs[am.ID] = struct{}{}
+	}
+
+	replaced := make(map[string]struct{})
+	for _, am := range e.available {
+		if len(am.Replaces) == 0 {
+			continue
+		}
+
+		n := 0
+		for _, id := range am.Replaces {
+			replaced[id] = struct{}{}
+			if _, ok := appliedIDs[id]; ok {
+				n++
+			}
+		}
+
+		_, applied := appliedIDs[am.ID]
+		if applied || n == 0 {
+			continue
+		}
+		if _, lastApplied := appliedIDs[am.Replaces[len(am.Replaces)-1]]; !lastApplied {
+			e.log.Error("only some migrations of a squashed migration are applied. Apply the remaining original migrations first",
+				"migration_id", am.ID, "replaces_amount", len(am.Replaces), "applied_amount", n)
+			return nil, ErrIntegrityProtection
+		}
+
+		// the last replaced migration was applied -> the squashed migration is applied too
+		r.squashed = append(r.squashed, am)
+	}
+
+	// applied migrations that were replaced (and aren't available anymore) are
+	// known and must neither be rolled back nor be treated as holes
+	for _, a := range e.applied {
+		_, isReplaced := replaced[a.ID]
+		_, isAvailable := availableIDs[a.ID]
+		if isReplaced && !isAvailable {
+			r.replaced = append(r.replaced, a)
+		}
+	}
+
+	return r, nil
+}
+
+// recorded returns all migrations recorded without executing them.
+func (r *appliedResolution) recorded() []*AvailableMigration {
+	recorded := make([]*AvailableMigration, 0, len(r.baseline)+len(r.squashed))
+	recorded = append(recorded, r.baseline...)
+	return append(recorded, r.squashed...)
+}
+
+// resolvedApplied returns the applied migrations as they are after the
+// resolution was recorded: replaced migrations are removed and recorded
+// migrations are added. It doesn't change e.applied.
+func (e *exec) resolvedApplied(r *appliedResolution) []*Migration {
+	replaced := make(map[string]struct{}, len(r.replaced))
+	for _, a := range r.replaced {
+		replaced[a.ID] = struct{}{}
+	}
+
+	resolved := make([]*Migration, 0, len(e.applied)+len(r.baseline)+len(r.squashed))
+	for _, a := range e.applied {
+		if _, ok := replaced[a.ID]; !ok {
+			resolved = append(resolved, a)
+		}
+	}
+	for _, am := range r.recorded() {
+		resolved = append(resolved, &Migration{ID: am.ID, Hash: am.Hash, Executor: BaselineExecutor})
+	}
+
+	sortApplied(resolved, e.optOrder)
+	return resolved
+}
+
+// resolveReplacedMigrations records the baseline of an empty meta-storage
+// (see BaselineOnEmptyMeta) and squashed migrations whose replaced migrations
+// are applied, and removes replaced migrations from the applied list.
+func (e *exec) resolveReplacedMigrations() error {
+	r, err := e.resolveApplied()
+	if err != nil {
+		return err
+	}
+
+	if len(r.baseline) > 0 {
+		e.log.Info("meta-storage is empty. Recording baseline", "up_to_id", e.optBaselineOnEmptyMeta)
+		err = e.recordBaseline(r.baseline)
+		if err != nil {
+			return err
+		}
+		e.log.Info("baseline successful", "recorded_amount", len(r.baseline))
+	}
+
+	if len(r.squashed) > 0 {
+		deployment, err := genBaselineDeploymentID()
+		if err != nil {
+			e.log.Error("failed to generate deployment id", "error", err)
+			return err
+		}
+		for dOrder, am := range r.squashed {
+			e.log.Info("replaced migrations are applied. Recording squashed migration as applied", "migration_id", am.ID)
+			err = e.recordWithoutExecution(am, deployment, dOrder)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	replaced := make(map[string]struct{}, len(r.replaced))
+	for _, a := range r.replaced {
+		e.log.Debug("applied migration was replaced by a squashed migration", "migration_id", a.ID)
+		replaced[a.ID] = struct{}{}
+	}
+	kept := make([]*Migration, 0, len(e.applied))
+	for _, a := range e.applied {
+		if _, ok := replaced[a.ID]; !ok {
+			kept = append(kept, a)
+		}
+	}
+	e.applied = kept
+	e.replacedApplied = append(e.replacedApplied, r.replaced...)
+
+	return nil
+}
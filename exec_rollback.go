@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sort"
+	"time"
 )
 
 func (e *exec) stageRollback() error {
@@ -17,11 +19,9 @@ func (e *exec) stageRollback() error {
 	e.log.Info("found n migrations in database that can rollback using provided down migrations", "n", len(e.unknownApplied))
 
 	var reversed []*Migration
+	var downs []policyTarget
 	for idx := len(e.unknownApplied) - 1; idx >= 0; idx-- {
-		reversed = append(reversed, e.unknownApplied[idx])
-	}
-
-	for _, u := range reversed {
+		u := e.unknownApplied[idx]
 		down := &ParsedMigration{}
 		err := json.Unmarshal(*u.Down, down)
 		if err != nil {
@@ -29,9 +29,27 @@ func (e *exec) stageRollback() error {
 			return err
 		}
 
+		reversed = append(reversed, u)
+		downs = append(downs, policyTarget{id: u.ID, parsed: down})
+	}
+
+	// stored down migrations are checked like up migrations before anything is
+	// reverted
+	if len(e.optPolicy) > 0 {
+		err := enforcePolicy(downs, e.dialect(), e.optPolicy, e.log)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, u := range reversed {
+		down := downs[i].parsed
+
 		e.log.Info("using parsed down migration to rollback", "migration_id", u.ID)
 
-		err = e.migrateWithSqlStatements(down, func(execDestination DBTarget) error {
+		e.currentMigration = u.ID
+		started := time.Now()
+		err := e.migrateWithSqlStatements(down, func(execDestination DBTarget) error {
 			err := e.driverAsDatabaseDriver.DeleteMigration(u.ID, execDestination)
 			if err != nil {
 				e.log.Error("failed to delete migration meta entry, although down migration succeeded before",
@@ -41,11 +59,14 @@ func (e *exec) stageRollback() error {
 			e.log.Debug("deleted meta entry successful", "migration_id", u.ID)
 			return nil
 		})
+		e.notifyRollback(u.ID, time.Since(started), err)
 		if err != nil {
 			e.log.Error("failed to migrate down", "error", err)
 			return err
 		}
 
+		e.reverted = append(e.reverted, u.ID)
+
 		// delete the migration we performed a rollback from the applied list
 		for i := range e.applied {
 			if e.applied[i].ID == u.ID {
@@ -70,3 +91,52 @@ func allUnknownProvideParsedDown(unknown []*Migration, log *slog.Logger) bool {
 	}
 	return ok
 }
+
+func (e *exec) stageRollbackSteps(steps int) ([]*Migration, error) {
+	e.log.Debug("rollback steps", "steps", steps)
+
+	if !e.driverIsDatabaseDriver {
+		e.log.Error("underlying driver isn't a DatabaseDriver! No way to apply Down migrations")
+		return nil, fmt.Errorf("adapt: rollback requires a DatabaseDriver")
+	}
+	if steps < 1 {
+		return nil, fmt.Errorf("adapt: rollback steps must be at least 1")
+	}
+	if steps > len(e.applied) {
+		e.log.Error("cannot rollback more migrations than applied", "steps", steps, "applied_amount", len(e.applied))
+		return nil, fmt.Errorf("adapt: cannot rollback %d migrations, because only %d are applied", steps, len(e.applied))
+	}
+
+	// select the last applied migrations in the order they were applied
+	byApplication := make([]*Migration, len(e.applied))
+	copy(byApplication, e.applied)
+	sort.SliceStable(byApplication, func(i, j int) bool {
+		a, b := byApplication[i], byApplication[j]
+		if !a.Started.Equal(b.Started) {
+			return a.Started.Before(b.Started)
+		}
+		if a.Deployment == b.Deployment {
+			return a.DeploymentOrder < b.DeploymentOrder
+		}
+		return false
+	})
+	e.unknownApplied = byApplication[len(byApplication)-steps:]
+
+	err := e.runStage(StageRollback, e.stageRollback)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.optRecordSchemaSnapshot {
+		err = e.recordSchemaSnapshot()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var reverted []*Migration
+	for idx := len(e.unknownApplied) - 1; idx >= 0; idx-- {
+		reverted = append(reverted, e.unknownApplied[idx])
+	}
+	return reverted, nil
+}
@@ -0,0 +1,132 @@
+package adapt
+
+import (
+	"fmt"
+	"time"
+)
+
+func (e *exec) schemaDrivers() (SchemaInspector, DriverJournal, error) {
+	inspector, ok := e.driver.(SchemaInspector)
+	if !ok {
+		e.log.Error("driver doesn't implement SchemaInspector", "driver", e.driver.Name())
+		return nil, nil, fmt.Errorf("adapt: schema snapshots require a Driver implementing SchemaInspector")
+	}
+	journal, ok := asJournal(e.driver)
+	if !ok {
+		e.log.Error("driver doesn't implement DriverJournal", "driver", e.driver.Name())
+		return nil, nil, fmt.Errorf("adapt: schema snapshots require a Driver implementing DriverJournal")
+	}
+	return inspector, journal, nil
+}
+
+// lastSchemaSnapshot returns the last JournalSchemaSnapshot entry or nil
+func lastSchemaSnapshot(journal DriverJournal) (*JournalEntry, error) {
+	entries, err := journal.ListJournalEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var last *JournalEntry
+	for _, entry := range entries {
+		if entry.Kind == JournalSchemaSnapshot {
+			last = entry
+		}
+	}
+	return last, nil
+}
+
+// recordSchemaSnapshot stores the hash of the current schema as a journal
+// entry. A snapshot is only recorded when migrations were applied or reverted,
+// or when none was recorded so far. Otherwise, the previous snapshot is kept,
+// so that drift stays detectable by CheckDrift.
+func (e *exec) recordSchemaSnapshot() error {
+	inspector, journal, err := e.schemaDrivers()
+	if err != nil {
+		return err
+	}
+
+	if len(e.deployed) == 0 && len(e.reverted) == 0 {
+		last, err := lastSchemaSnapshot(journal)
+		if err != nil {
+			return err
+		}
+		if last != nil {
+			e.log.Debug("no migrations applied or reverted. Keeping previous schema snapshot", "hash", last.Detail)
+			return nil
+		}
+	}
+
+	snapshot, err := inspector.InspectSchema()
+	if err != nil {
+		e.log.Error("failed to inspect schema", "error", err)
+		return err
+	}
+
+	var lastID string
+	if len(e.applied) > 0 {
+		lastID = e.applied[len(e.applied)-1].ID
+	}
+	reason := "post-migration schema snapshot"
+	if len(e.deployed) > 0 {
+		lastID = e.deployed[len(e.deployed)-1]
+	} else if len(e.reverted) > 0 {
+		reason = "post-rollback schema snapshot"
+	}
+
+	hash := snapshot.Hash()
+	err = journal.AddJournalEntry(&JournalEntry{
+		Kind:        JournalSchemaSnapshot,
+		MigrationID: lastID,
+		Executor:    e.executor,
+		Created:     time.Now().UTC(),
+		Reason:      reason,
+		Detail:      hash,
+	})
+	if err != nil {
+		e.log.Error("failed to record schema snapshot", "error", err)
+		return err
+	}
+
+	e.log.Info("recorded schema snapshot", "migration_id", lastID, "hash", hash, "objects_amount", len(snapshot.Objects))
+	return nil
+}
+
+func (e *exec) stageCheckDrift() (*DriftReport, error) {
+	e.log.Debug("check drift")
+
+	inspector, journal, err := e.schemaDrivers()
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := lastSchemaSnapshot(journal)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		e.log.Error("no schema snapshot recorded. Use RecordSchemaSnapshot when migrating")
+		return nil, ErrNoSchemaSnapshot
+	}
+
+	live, err := inspector.InspectSchema()
+	if err != nil {
+		e.log.Error("failed to inspect schema", "error", err)
+		return nil, err
+	}
+
+	report := &DriftReport{
+		MigrationID:  last.MigrationID,
+		Recorded:     last.Created,
+		RecordedHash: last.Detail,
+		Live:         live,
+		LiveHash:     live.Hash(),
+	}
+	report.Drifted = report.LiveHash != report.RecordedHash
+
+	if report.Drifted {
+		e.log.Warn("schema drifted from recorded snapshot", "migration_id", report.MigrationID, "recorded_hash", report.RecordedHash, "live_hash", report.LiveHash)
+	} else {
+		e.log.Info("schema matches recorded snapshot", "migration_id", report.MigrationID, "hash", report.LiveHash)
+	}
+	return report, nil
+}
@@ -2,22 +2,51 @@ package adapt
 
 import (
 	"log/slog"
+	"strings"
 )
 
 func (e *exec) stageStart() error {
 	e.log.Debug("start")
 
-	// compare local against store
-	unknown, err := unknownAppliedMigrations(e.applied, e.available, !e.optDisableHashIntegrityChecks, e.log)
+	// baseline empty meta-storage if requested and resolve applied migrations
+	// that were squashed into a single migration
+	err := e.resolveReplacedMigrations()
+	if err != nil {
+		return err
+	}
+
+	// compare local against store. Migrations filtered by their tags are still
+	// known, so applying them with different tags never triggers a rollback
+	unknown, err := unknownAppliedMigrations(e.applied, e.known(), !e.optDisableHashIntegrityChecks, e.optHookChangePolicy, e.log)
 	if err != nil {
 		return err
 	}
 	e.unknownApplied = unknown
 
+	// replace stored hashes produced by older algorithms
+	err = e.upgradeHashes()
+	if err != nil {
+		return err
+	}
+
+	// store hashes of migrations applied without one (e.g. a Hook that gained
+	// a Fingerprint), so they are protected from now on
+	err = e.storeMissingHashes()
+	if err != nil {
+		return err
+	}
+
+	// store hashes of changed hooks accepted by HookChangeWarn, so the
+	// warning is only reported once
+	err = e.acceptChangedHooks()
+	if err != nil {
+		return err
+	}
+
 	// branch between rollback and migrate
 	if len(e.unknownApplied) > 0 {
 		e.log.Debug("found unknown migrations. Starting with rollback protocol", "unknown_migrations", len(e.unknownApplied))
-		err = e.stageRollback()
+		err = e.runStage(StageRollback, e.stageRollback)
 		if err != nil {
 			e.log.Error("rollback procedure failed", "error", err)
 			return err
@@ -26,10 +55,18 @@ func (e *exec) stageStart() error {
 		e.log.Debug("all stored migrations are known. Continuing with migration")
 	}
 
-	return e.stageMigrate()
+	err = e.runStage(StageMigrate, e.stageMigrate)
+	if err != nil {
+		return err
+	}
+
+	if e.optRecordSchemaSnapshot {
+		return e.recordSchemaSnapshot()
+	}
+	return nil
 }
 
-func unknownAppliedMigrations(applied []*Migration, available []*AvailableMigration, performHashIntegrityChecks bool, log *slog.Logger) ([]*Migration, error) {
+func unknownAppliedMigrations(applied []*Migration, available []*AvailableMigration, performHashIntegrityChecks bool, hookPolicy HookChangePolicy, log *slog.Logger) ([]*Migration, error) {
 	searchLocal := func(id string) *AvailableMigration {
 		for _, local := range available {
 			if local.ID == id {
@@ -39,10 +76,6 @@ func unknownAppliedMigrations(applied []*Migration, available []*AvailableMigrat
 		return nil
 	}
 
-	hashEqual := func(h1 *string, h2 *string) bool {
-		return h1 == nil || h2 == nil || *h1 == *h2
-	}
-
 	var unknown []*Migration
 	for _, a := range applied {
 		local := searchLocal(a.ID)
@@ -51,9 +84,15 @@ func unknownAppliedMigrations(applied []*Migration, available []*AvailableMigrat
 			continue
 		}
 
-		if performHashIntegrityChecks && !hashEqual(a.Hash, local.Hash) {
+		if performHashIntegrityChecks && !hashEqual(a.Hash, local) {
+			if _, isHook := local.Source.(HookSource); isHook && hookPolicy == HookChangeWarn {
+				log.Warn("hash of local hook migration changed. Continuing, because hook changes are configured to be warnings",
+					"migration_id", a.ID, "local_hash", hashString(local.Hash), "storage_hash", hashString(a.Hash))
+				continue
+			}
+
 			log.Error("hash of local migration changed. Aborting to protect integrity, as changes to already applied scripts aren't allowed",
-				"migration_id", a.ID, "local_hash", *local.Hash, "storage_hash", *a.Hash)
+				"migration_id", a.ID, "local_hash", hashString(local.Hash), "storage_hash", hashString(a.Hash))
 			return nil, ErrIntegrityProtection
 		}
 
@@ -68,3 +107,190 @@ func unknownAppliedMigrations(applied []*Migration, available []*AvailableMigrat
 
 	return unknown, nil
 }
+
+// hashEqual reports whether the stored hash matches the local migration. When
+// the local migration provides a ParsedMigration the algorithm is selected by
+// the prefix of the stored hash (see ParsedMigration.MatchesHash), so hashes
+// stored by older versions of adapt are still understood. A missing hash on
+// either side can't be compared and is treated as equal, except when a Hook
+// that previously had a Fingerprint (see Hook.Hash) no longer provides one. A
+// missing stored hash is filled in by storeMissingHashes, so later changes are
+// detected.
+func hashEqual(stored *string, local *AvailableMigration) bool {
+	if stored == nil {
+		return true
+	}
+	if local.Hash == nil {
+		return !strings.HasPrefix(*stored, HashPrefixHookV1)
+	}
+	if local.ParsedUp != nil {
+		return local.ParsedUp.MatchesHash(*stored)
+	}
+	return *stored == *local.Hash
+}
+
+// outdatedHashes returns all applied migrations whose stored hash matches the
+// local migration, but was produced by a different hash algorithm than the
+// one currently used.
+func outdatedHashes(applied []*Migration, available []*AvailableMigration) []*Migration {
+	localByID := make(map[string]*AvailableMigration, len(available))
+	for _, local := range available {
+		localByID[local.ID] = local
+	}
+
+	var outdated []*Migration
+	for _, a := range applied {
+		local, ok := localByID[a.ID]
+		if !ok || a.Hash == nil || local.Hash == nil || *a.Hash == *local.Hash {
+			continue
+		}
+		if hashEqual(a.Hash, local) {
+			outdated = append(outdated, a)
+		}
+	}
+	return outdated
+}
+
+func (e *exec) upgradeHashes() error {
+	if e.optDisableHashIntegrityChecks || e.optDisableHashUpgrades {
+		return nil
+	}
+
+	outdated := outdatedHashes(e.applied, e.available)
+	if len(outdated) == 0 {
+		return nil
+	}
+
+	updater, ok := asHashUpdater(e.driver)
+	if !ok {
+		e.log.Debug("driver doesn't support updating hashes. Keeping outdated hashes", "outdated_amount", len(outdated))
+		return nil
+	}
+
+	for _, a := range outdated {
+		local := e.findAvailable(a.ID)
+		err := updater.UpdateMigrationHash(a.ID, local.Hash)
+		if err != nil {
+			e.log.Error("failed to upgrade stored hash", "migration_id", a.ID, "error", err)
+			return err
+		}
+
+		e.log.Info("upgraded stored hash to current algorithm", "migration_id", a.ID, "old_hash", *a.Hash, "new_hash", *local.Hash)
+		a.Hash = local.Hash
+	}
+
+	return nil
+}
+
+// missingHashes returns all applied migrations stored without a hash, whose
+// local migration provides one now, e.g. a Hook that gained a Fingerprint.
+func missingHashes(applied []*Migration, available []*AvailableMigration) []*Migration {
+	localByID := make(map[string]*AvailableMigration, len(available))
+	for _, local := range available {
+		localByID[local.ID] = local
+	}
+
+	var missing []*Migration
+	for _, a := range applied {
+		local, ok := localByID[a.ID]
+		if ok && a.Hash == nil && local.Hash != nil {
+			missing = append(missing, a)
+		}
+	}
+	return missing
+}
+
+// storeMissingHashes stores the current hash of applied migrations that were
+// stored without one, so that they are protected by the hash integrity checks
+// from now on.
+func (e *exec) storeMissingHashes() error {
+	if e.optDisableHashIntegrityChecks {
+		return nil
+	}
+
+	missing := missingHashes(e.applied, e.available)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	updater, ok := asHashUpdater(e.driver)
+	if !ok {
+		e.log.Warn("driver doesn't support updating hashes. Applied migrations without a stored hash aren't protected against changes", "missing_amount", len(missing))
+		return nil
+	}
+
+	for _, a := range missing {
+		local := e.findAvailable(a.ID)
+		err := updater.UpdateMigrationHash(a.ID, local.Hash)
+		if err != nil {
+			e.log.Error("failed to store missing hash", "migration_id", a.ID, "error", err)
+			return err
+		}
+
+		e.log.Info("stored hash of migration applied without one", "migration_id", a.ID, "new_hash", *local.Hash)
+		a.Hash = local.Hash
+	}
+
+	return nil
+}
+
+// acceptChangedHooks replaces the stored hashes of changed Hook migrations
+// when hook changes are configured to be warnings (see HookChangeWarn).
+func (e *exec) acceptChangedHooks() error {
+	if e.optDisableHashIntegrityChecks || e.optHookChangePolicy != HookChangeWarn {
+		return nil
+	}
+
+	for _, a := range e.applied {
+		local := e.findAvailable(a.ID)
+		if local == nil || hashEqual(a.Hash, local) {
+			continue
+		}
+		if _, isHook := local.Source.(HookSource); !isHook {
+			continue
+		}
+
+		updater, ok := asHashUpdater(e.driver)
+		if !ok {
+			e.log.Debug("driver doesn't support updating hashes. Changed hook migration is reported again next time", "migration_id", a.ID)
+			return nil
+		}
+		err := updater.UpdateMigrationHash(a.ID, local.Hash)
+		if err != nil {
+			e.log.Error("failed to store hash of changed hook migration", "migration_id", a.ID, "error", err)
+			return err
+		}
+
+		e.log.Info("stored hash of changed hook migration", "migration_id", a.ID, "old_hash", hashString(a.Hash), "new_hash", hashString(local.Hash))
+		a.Hash = local.Hash
+	}
+
+	return nil
+}
+
+// hashString formats an optional hash for log output
+func hashString(hash *string) string {
+	if hash == nil {
+		return "<nil>"
+	}
+	return *hash
+}
+
+// known returns all available and skipped migrations.
+func (e *exec) known() []*AvailableMigration {
+	if len(e.skipped) == 0 {
+		return e.available
+	}
+	known := make([]*AvailableMigration, 0, len(e.available)+len(e.skipped))
+	known = append(known, e.available...)
+	return append(known, e.skipped...)
+}
+
+func (e *exec) findAvailable(id string) *AvailableMigration {
+	for _, local := range e.available {
+		if local.ID == id {
+			return local
+		}
+	}
+	return nil
+}
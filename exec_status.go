@@ -0,0 +1,81 @@
+package adapt
+
+import "sort"
+
+func (e *exec) stageStatus() ([]*MigrationStatus, error) {
+	e.log.Debug("status")
+
+	statuses, err := e.statuses()
+	if err != nil {
+		return nil, err
+	}
+
+	e.log.Info("status successful", "migrations_amount", len(statuses))
+	return statuses, nil
+}
+
+// statuses reports the state of all available and applied migrations. The
+// baseline and squashed migrations Migrate would record without executing
+// them are reported as applied (see resolveApplied).
+func (e *exec) statuses() ([]*MigrationStatus, error) {
+	r, err := e.resolveApplied()
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]bool, len(r.baseline)+len(r.squashed))
+	for _, am := range r.recorded() {
+		recorded[am.ID] = true
+	}
+
+	applied := make(map[string]*Migration, len(e.applied))
+	for _, a := range e.applied {
+		applied[a.ID] = a
+	}
+	for _, a := range r.replaced {
+		delete(applied, a.ID)
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(e.available)+len(e.skipped)+len(e.repeatable)+len(e.applied))
+	add := func(am *AvailableMigration, notApplied StatusState) {
+		s := &MigrationStatus{ID: am.ID, State: notApplied, Available: am}
+		if a, ok := applied[am.ID]; ok {
+			s.State = StatusApplied
+			s.Applied = a
+			delete(applied, am.ID)
+		} else if recorded[am.ID] {
+			s.State = StatusApplied
+		}
+		statuses = append(statuses, s)
+	}
+	for _, am := range e.available {
+		add(am, StatusPending)
+	}
+	for _, am := range e.skipped {
+		add(am, StatusSkipped)
+	}
+	changed := make(map[string]bool)
+	for _, am := range e.changedRepeatable() {
+		changed[am.ID] = true
+	}
+	for _, am := range e.repeatable {
+		s := &MigrationStatus{ID: am.ID, State: StatusPending, Available: am, Applied: e.findAppliedRepeatable(am.ID)}
+		if !changed[am.ID] {
+			s.State = StatusApplied
+		}
+		statuses = append(statuses, s)
+	}
+	for _, a := range r.replaced {
+		statuses = append(statuses, &MigrationStatus{ID: a.ID, State: StatusReplaced, Applied: a})
+	}
+	for _, a := range e.applied {
+		if _, ok := applied[a.ID]; ok {
+			statuses = append(statuses, &MigrationStatus{ID: a.ID, State: StatusUnknown, Applied: a})
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return e.optOrder(statuses[i].ID, statuses[j].ID) < 0
+	})
+
+	return statuses, nil
+}
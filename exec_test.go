@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -43,6 +44,27 @@ func Test_mergeSources(t *testing.T) {
 			{ID: "20201115_1214_init"},
 			{ID: "20201115_1717_undo-init"},
 		}, false},
+		{"combined up/down file", args{[]Source{
+			NewMemoryFSSource(map[string]string{
+				"20201115_1717_undo-init.sql": "-- +adapt Up\nDELETE DATABASE;\n-- +adapt Down\nCREATE DATABASE;",
+				"20201115_1214_init.up.sql":   "CREATE DATABASE",
+			}),
+		}}, []*AvailableMigration{
+			{ID: "20201115_1214_init"},
+			{ID: "20201115_1717_undo-init"},
+		}, false},
+		{"fs.FS source with directory", args{[]Source{
+			NewFSSource(fstest.MapFS{
+				"sql/20201115_1717_undo-init.up.sql": {Data: []byte("DELETE DATABASE")},
+				"other/20201115_1200_other.up.sql":   {Data: []byte("CREATE DATABASE")},
+			}, "sql"),
+			NewMemoryFSSource(map[string]string{
+				"20201115_1214_init.up.sql": "CREATE DATABASE",
+			}),
+		}}, []*AvailableMigration{
+			{ID: "20201115_1214_init"},
+			{ID: "20201115_1717_undo-init"},
+		}, false},
 		{"same id in multiple sources", args{[]Source{
 			NewMemoryFSSource(map[string]string{
 				"20201115_1717_undo-init.up.sql": "DELETE DATABASE",
@@ -115,7 +137,7 @@ func Test_mergeSources(t *testing.T) {
 				}
 			}
 
-			got, err := mergeSources(tt.args.sources, l)
+			got, _, err := mergeSources(tt.args.sources, LexicalOrder, tagFilter{}, l)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("mergeSources() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -200,11 +222,14 @@ func Test_unknownAppliedMigrations(t *testing.T) {
 	strPtr := func(s string) *string {
 		return &s
 	}
+	legacy := &ParsedMigration{UseTx: true, Stmts: []string{"CREATE TABLE a (id INT);"}}
+	changed := &ParsedMigration{UseTx: true, Stmts: []string{"CREATE TABLE a (id BIGINT);"}}
 
 	type args struct {
-		applied   []*Migration
-		available []*AvailableMigration
-		unknown   []string
+		applied    []*Migration
+		available  []*AvailableMigration
+		unknown    []string
+		hookPolicy HookChangePolicy
 	}
 	tests := []struct {
 		name    string
@@ -262,12 +287,45 @@ func Test_unknownAppliedMigrations(t *testing.T) {
 				{ID: "1", Hash: strPtr("LOCAL_HASH")},
 			},
 		}, true},
+		{"hook hash mismatch", args{
+			applied: []*Migration{
+				{ID: "1", Hash: Hook{Fingerprint: "v1"}.Hash()},
+			},
+			available: []*AvailableMigration{
+				{ID: "1", Hash: Hook{Fingerprint: "v2"}.Hash(), Source: NewCodeSource("1", Hook{Fingerprint: "v2"})},
+			},
+		}, true},
+		{"hook hash mismatch with warn policy", args{
+			applied: []*Migration{
+				{ID: "1", Hash: Hook{Fingerprint: "v1"}.Hash()},
+			},
+			available: []*AvailableMigration{
+				{ID: "1", Hash: Hook{Fingerprint: "v2"}.Hash(), Source: NewCodeSource("1", Hook{Fingerprint: "v2"})},
+			},
+			hookPolicy: HookChangeWarn,
+		}, false},
+		{"legacy hash match", args{
+			applied: []*Migration{
+				{ID: "1", Hash: legacy.LegacyHash()},
+			},
+			available: []*AvailableMigration{
+				{ID: "1", Hash: legacy.Hash(), ParsedUp: legacy},
+			},
+		}, false},
+		{"legacy hash mismatch", args{
+			applied: []*Migration{
+				{ID: "1", Hash: legacy.LegacyHash()},
+			},
+			available: []*AvailableMigration{
+				{ID: "1", Hash: changed.Hash(), ParsedUp: changed},
+			},
+		}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			l := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-			unknown, err := unknownAppliedMigrations(tt.args.applied, tt.args.available, true, l)
+			unknown, err := unknownAppliedMigrations(tt.args.applied, tt.args.available, true, tt.args.hookPolicy, l)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("unknownAppliedMigrations() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -287,13 +345,15 @@ func Test_unknownAppliedMigrations(t *testing.T) {
 
 func Test_findNeededMigrations(t *testing.T) {
 	type args struct {
-		applied   []*Migration
-		available []*AvailableMigration
+		applied        []*Migration
+		available      []*AvailableMigration
+		strictOrdering bool
 	}
 	tests := []struct {
-		name string
-		args args
-		want []*AvailableMigration
+		name    string
+		args    args
+		want    []*AvailableMigration
+		wantErr bool
 	}{
 		{"all applied", args{
 			applied: []*Migration{
@@ -306,7 +366,7 @@ func Test_findNeededMigrations(t *testing.T) {
 				{ID: "2"},
 				{ID: "3"},
 			},
-		}, []*AvailableMigration{}},
+		}, []*AvailableMigration{}, false},
 		{"db ahead of available", args{
 			applied: []*Migration{
 				{ID: "1"},
@@ -320,7 +380,7 @@ func Test_findNeededMigrations(t *testing.T) {
 				{ID: "2"},
 				{ID: "3"},
 			},
-		}, []*AvailableMigration{}},
+		}, []*AvailableMigration{}, false},
 		{"db behind available", args{
 			applied: []*Migration{
 				{ID: "1"},
@@ -337,7 +397,7 @@ func Test_findNeededMigrations(t *testing.T) {
 			{ID: "3"},
 			{ID: "4"},
 			{ID: "5"},
-		}},
+		}, false},
 		{"db empty", args{
 			applied: []*Migration{},
 			available: []*AvailableMigration{
@@ -347,7 +407,7 @@ func Test_findNeededMigrations(t *testing.T) {
 		}, []*AvailableMigration{
 			{ID: "1"},
 			{ID: "2"},
-		}},
+		}, false},
 		{"db with merch hole", args{
 			applied: []*Migration{
 				{ID: "1"},
@@ -362,7 +422,7 @@ func Test_findNeededMigrations(t *testing.T) {
 			},
 		}, []*AvailableMigration{
 			{ID: "3"},
-		}},
+		}, false},
 		{"db behind available and with merch hole", args{
 			applied: []*Migration{
 				{ID: "1"},
@@ -381,7 +441,7 @@ func Test_findNeededMigrations(t *testing.T) {
 			{ID: "3"},
 			{ID: "5"},
 			{ID: "7"},
-		}},
+		}, false},
 		{"with dates", args{
 			applied: []*Migration{
 				{ID: "20210110_1919_init.sql"},        // 1
@@ -400,15 +460,145 @@ func Test_findNeededMigrations(t *testing.T) {
 			{ID: "20210114_1012_improve-db.sql"},         // 3
 			{ID: "20210418_0112_improve-users.sql"},      // 5
 			{ID: "20210501_1114_add-analytics-user.sql"}, // 6
-		}},
+		}, false},
+		{"strict ordering without hole", args{
+			applied: []*Migration{
+				{ID: "1"},
+				{ID: "2"},
+			},
+			available: []*AvailableMigration{
+				{ID: "1"},
+				{ID: "2"},
+				{ID: "3"},
+			},
+			strictOrdering: true,
+		}, []*AvailableMigration{
+			{ID: "3"},
+		}, false},
+		{"strict ordering with hole", args{
+			applied: []*Migration{
+				{ID: "1"},
+				{ID: "4"},
+			},
+			available: []*AvailableMigration{
+				{ID: "1"},
+				{ID: "2"},
+				{ID: "3"},
+				{ID: "4"},
+				{ID: "5"},
+			},
+			strictOrdering: true,
+		}, nil, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			l := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-			if got := findNeededMigrations(tt.args.applied, tt.args.available, l); !reflect.DeepEqual(got, tt.want) {
+			got, err := findNeededMigrations(tt.args.applied, tt.args.available, tt.args.strictOrdering, l)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findNeededMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("findNeededMigrations() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_orderByDependencies(t *testing.T) {
+	tests := []struct {
+		name      string
+		available []*AvailableMigration
+		want      []string
+		wantErr   bool
+	}{
+		{"no dependencies", []*AvailableMigration{
+			{ID: "1"}, {ID: "2"}, {ID: "3"},
+		}, []string{"1", "2", "3"}, false},
+		{"dependency on later migration", []*AvailableMigration{
+			{ID: "1"}, {ID: "2", DependsOn: []string{"4"}}, {ID: "3"}, {ID: "4"},
+		}, []string{"1", "3", "4", "2"}, false},
+		{"transitive dependencies", []*AvailableMigration{
+			{ID: "1", DependsOn: []string{"3"}}, {ID: "2"}, {ID: "3", DependsOn: []string{"4"}}, {ID: "4"},
+		}, []string{"2", "4", "3", "1"}, false},
+		{"dependency on replaced migration", []*AvailableMigration{
+			{ID: "1", DependsOn: []string{"0_old"}}, {ID: "2", Replaces: []string{"0_old"}},
+		}, []string{"2", "1"}, false},
+		{"dependency not available", []*AvailableMigration{
+			{ID: "1", DependsOn: []string{"0_applied"}}, {ID: "2"},
+		}, []string{"1", "2"}, false},
+		{"cycle", []*AvailableMigration{
+			{ID: "1"}, {ID: "2", DependsOn: []string{"3"}}, {ID: "3", DependsOn: []string{"2"}},
+		}, nil, true},
+		{"self dependency", []*AvailableMigration{
+			{ID: "1", DependsOn: []string{"1"}},
+		}, nil, true},
+		{"dependency on own replaced migration", []*AvailableMigration{
+			{ID: "1"}, {ID: "2", Replaces: []string{"0_old"}, DependsOn: []string{"0_old"}},
+		}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			got, err := orderByDependencies(tt.available, l)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("orderByDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, am := range got {
+				ids = append(ids, am.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("orderByDependencies() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func Test_tagFilter_matches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter tagFilter
+		tags   []string
+		want   bool
+	}{
+		{"no filter", tagFilter{}, []string{"dev"}, true},
+		{"untagged with include", tagFilter{include: []string{"dev"}}, nil, true},
+		{"untagged with exclude", tagFilter{exclude: []string{"dev"}}, nil, true},
+		{"included", tagFilter{include: []string{"dev", "staging"}}, []string{"seed", "staging"}, true},
+		{"not included", tagFilter{include: []string{"dev"}}, []string{"eu"}, false},
+		{"excluded", tagFilter{exclude: []string{"seed"}}, []string{"dev", "seed"}, false},
+		{"exclude takes precedence", tagFilter{include: []string{"dev"}, exclude: []string{"seed"}}, []string{"dev", "seed"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(tt.tags); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_outdatedHashes(t *testing.T) {
+	parsed := &ParsedMigration{UseTx: true, Stmts: []string{"CREATE TABLE a (id INT);"}}
+	changed := &ParsedMigration{UseTx: true, Stmts: []string{"CREATE TABLE a (id BIGINT);"}}
+
+	applied := []*Migration{
+		{ID: "1", Hash: parsed.LegacyHash()},
+		{ID: "2", Hash: parsed.Hash()},
+		{ID: "3", Hash: parsed.LegacyHash()},
+		{ID: "4"},
+	}
+	available := []*AvailableMigration{
+		{ID: "1", Hash: parsed.Hash(), ParsedUp: parsed},
+		{ID: "2", Hash: parsed.Hash(), ParsedUp: parsed},
+		{ID: "3", Hash: changed.Hash(), ParsedUp: changed},
+		{ID: "4", Hash: parsed.Hash(), ParsedUp: parsed},
+	}
+
+	got := outdatedHashes(applied, available)
+	if len(got) != 1 || got[0].ID != "1" {
+		t.Errorf("outdatedHashes() = %v, want only migration 1", got)
+	}
+}
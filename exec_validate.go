@@ -0,0 +1,205 @@
+package adapt
+
+import (
+	"regexp"
+	"strings"
+)
+
+func (e *exec) stageValidate() Diagnostics {
+	e.log.Debug("validate")
+
+	var diagnostics Diagnostics
+
+	// collect the migrations of all sources. Unlike mergeSources problems are
+	// reported and the remaining migrations are still checked.
+	providedBy := make(map[string]int)
+	var known []*AvailableMigration
+	for i, src := range e.sources {
+		if err := src.Init(e.log); err != nil {
+			diagnostics.add(SeverityError, DiagnosticSource, "", "source %d failed to initialize: %v", i, err)
+			continue
+		}
+		ids, err := src.ListMigrations()
+		if err != nil {
+			diagnostics.add(SeverityError, DiagnosticSource, "", "source %d failed to list migrations: %v", i, err)
+			continue
+		}
+
+		for _, id := range ids {
+			if first, ok := providedBy[id]; ok {
+				diagnostics.add(SeverityError, DiagnosticDuplicateID, id, "migration is provided by source %d and %d", first, i)
+				continue
+			}
+			providedBy[id] = i
+
+			am := &AvailableMigration{
+				ID:     id,
+				Source: src,
+			}
+			if err = am.Enrich(e.log); err != nil {
+				diagnostics.add(SeverityError, DiagnosticParse, id, "parsing up migration failed: %v", err)
+				continue
+			}
+			known = append(known, am)
+		}
+	}
+
+	sortAvailable(known, e.optOrder)
+	for _, am := range known {
+		e.validateMigration(am, &diagnostics)
+	}
+	e.validateDependencies(known, &diagnostics)
+
+	e.log.Info("validate finished", "migrations_amount", len(known), "diagnostics_amount", len(diagnostics), "errors_amount", len(diagnostics.Errors()))
+	return diagnostics
+}
+
+func (e *exec) validateMigration(am *AvailableMigration, diagnostics *Diagnostics) {
+	for _, validate := range e.optIDValidators {
+		if err := validate(am.ID); err != nil {
+			diagnostics.add(SeverityError, DiagnosticInvalidID, am.ID, "%v", err)
+			break
+		}
+	}
+
+	switch src := am.Source.(type) {
+	case SqlStatementsSource:
+		e.validateParsed(am.ID, "up", am.ParsedUp, diagnostics)
+		for _, v := range checkPolicy(am.ParsedUp, e.dialect(), e.optPolicy) {
+			diagnostics.add(SeverityError, DiagnosticPolicy, am.ID, "up statement %d violates policy %q: %s", v.stmt, v.rule.Name, v.rule.Reason)
+		}
+
+		down, err := src.GetParsedDownMigration(am.ID)
+		if err != nil {
+			diagnostics.add(SeverityError, DiagnosticParse, am.ID, "parsing down migration failed: %v", err)
+			return
+		}
+		if down == nil {
+			if !am.Repeatable {
+				diagnostics.add(SeverityWarning, DiagnosticMissingDown, am.ID, "migration has no down migration")
+			}
+			return
+		}
+		e.validateParsed(am.ID, "down", down, diagnostics)
+	case HookSource:
+		hook := src.GetHook(am.ID)
+		if hook.MigrateUp == nil && hook.MigrateUpDB == nil && hook.MigrateUpTx == nil {
+			diagnostics.add(SeverityError, DiagnosticEmptyMigration, am.ID, "hook has neither MigrateUp, MigrateUpDB nor MigrateUpTx")
+		}
+
+		var down *ParsedMigration
+		if hook.MigrateDown != nil {
+			down = hook.MigrateDown()
+		}
+		if down == nil {
+			if !am.Repeatable {
+				diagnostics.add(SeverityWarning, DiagnosticMissingDown, am.ID, "hook has no down migration")
+			}
+			return
+		}
+		e.validateParsed(am.ID, "down", down, diagnostics)
+	}
+}
+
+func (e *exec) validateParsed(id string, direction string, parsed *ParsedMigration, diagnostics *Diagnostics) {
+	if len(parsed.Stmts) == 0 {
+		diagnostics.add(SeverityWarning, DiagnosticEmptyMigration, id, "%s migration contains no statements", direction)
+		return
+	}
+
+	if !parsed.UseTx && len(parsed.Stmts) > 1 {
+		diagnostics.add(SeverityWarning, DiagnosticNoTransaction, id, "%s migration uses NoTransaction with %d statements and can be left partially applied", direction, len(parsed.Stmts))
+	}
+
+	for i, stmt := range parsed.Stmts {
+		normalized := strings.ToUpper(normalizeStatement(stmt))
+		if len(strings.Trim(normalized, "; ")) == 0 {
+			diagnostics.add(SeverityError, DiagnosticEmptyStatement, id, "%s statement %d is empty", direction, i+1)
+			continue
+		}
+
+		if !parsed.UseTx {
+			continue
+		}
+		for _, hazard := range dialectHazards {
+			if hazard.dialect != e.optDialect || !hazard.pattern.MatchString(normalized) {
+				continue
+			}
+			if hazard.onlyMultiple && len(parsed.Stmts) == 1 {
+				continue
+			}
+			diagnostics.add(hazard.severity, DiagnosticDialectHazard, id, "%s statement %d %s", direction, i+1, hazard.message)
+		}
+	}
+}
+
+func (e *exec) validateDependencies(known []*AvailableMigration, diagnostics *Diagnostics) {
+	providers := dependencyProviders(known)
+	selfDependent := false
+	for _, am := range known {
+		for _, dep := range am.DependsOn {
+			if _, ok := providers[dep]; !ok {
+				diagnostics.add(SeverityWarning, DiagnosticDependency, am.ID, "depends on unknown migration %q, which must already be applied", dep)
+			}
+		}
+		if dep, ok := selfDependency(am, providers); ok {
+			diagnostics.add(SeverityError, DiagnosticDependency, am.ID, "dependency cycle: depends on itself through %q", dep)
+			selfDependent = true
+		}
+	}
+
+	// self dependencies are already reported for their migration
+	if selfDependent {
+		return
+	}
+	if _, err := orderByDependencies(known, e.log); err != nil {
+		diagnostics.add(SeverityError, DiagnosticDependency, "", "%v", err)
+	}
+}
+
+// dialectHazard describes a statement that misbehaves when executed inside a
+// transaction
+type dialectHazard struct {
+	dialect  Dialect
+	pattern  *regexp.Regexp
+	severity Severity
+	message  string
+	// onlyMultiple restricts the hazard to migrations with multiple statements
+	onlyMultiple bool
+}
+
+// dialectHazards are matched against the upper-cased normalized statements of
+// migrations using a transaction
+var dialectHazards = []dialectHazard{
+	{
+		dialect:  DialectPostgres,
+		pattern:  regexp.MustCompile(`^(CREATE (UNIQUE )?INDEX|DROP INDEX|REINDEX\b.*) CONCURRENTLY\b`),
+		severity: SeverityError,
+		message:  "cannot run CONCURRENTLY inside a transaction (use NoTransaction)",
+	},
+	{
+		dialect:  DialectPostgres,
+		pattern:  regexp.MustCompile(`^(VACUUM|ALTER SYSTEM|(CREATE|DROP) (DATABASE|TABLESPACE))\b`),
+		severity: SeverityError,
+		message:  "cannot run inside a transaction (use NoTransaction)",
+	},
+	{
+		dialect:      DialectMySQL,
+		pattern:      regexp.MustCompile(`^(CREATE|ALTER|DROP|RENAME|TRUNCATE)\b`),
+		severity:     SeverityWarning,
+		message:      "causes an implicit commit, so the transaction cannot roll back the migration's other statements",
+		onlyMultiple: true,
+	},
+	{
+		dialect:  DialectSQLite,
+		pattern:  regexp.MustCompile(`^VACUUM\b`),
+		severity: SeverityError,
+		message:  "cannot run inside a transaction (use NoTransaction)",
+	},
+	{
+		dialect:  DialectSQLite,
+		pattern:  regexp.MustCompile(`^PRAGMA (FOREIGN_KEYS|JOURNAL_MODE)\b`),
+		severity: SeverityWarning,
+		message:  "has no effect inside a transaction (use NoTransaction)",
+	},
+}
@@ -0,0 +1,94 @@
+package adapt
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HashPrefixV2 is the prefix of hashes calculated by ParsedMigration.Hash
+	HashPrefixV2 = "sha256v2:"
+	// HashPrefixV2Normalized is the prefix of hashes calculated by
+	// ParsedMigration.NormalizedHash
+	HashPrefixV2Normalized = "sha256v2n:"
+)
+
+// Hash calculates a unique hash for the ParsedMigration. It includes the UseTx
+// field and every single statement from the Stmts field. Every element is
+// prefixed with its length, so that different statement boundaries always
+// result in different hashes. The returned hash is prefixed with HashPrefixV2
+// to identify the algorithm that produced it.
+func (m *ParsedMigration) Hash() *string {
+	return m.hashV2(HashPrefixV2, func(stmt string) string { return stmt })
+}
+
+// NormalizedHash calculates a hash like Hash, but normalizes every statement
+// before hashing it: lines that only contain a "--" comment are removed and all
+// whitespace sequences are collapsed to a single space. Therefore, formatting
+// changes to already applied migrations don't change the hash. The returned
+// hash is prefixed with HashPrefixV2Normalized.
+func (m *ParsedMigration) NormalizedHash() *string {
+	return m.hashV2(HashPrefixV2Normalized, normalizeStatement)
+}
+
+// LegacyHash calculates the hash used by adapt before versioned hashes were
+// introduced. It concatenates all statements without separators and has no
+// prefix. It is only used to compare against hashes stored by older versions.
+func (m *ParsedMigration) LegacyHash() *string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(strconv.FormatBool(m.UseTx)))
+	for _, stmt := range m.Stmts {
+		// hash.Write never returns an error as to it's documentation
+		_, _ = h.Write([]byte(stmt))
+	}
+	hashStr := hex.EncodeToString(h.Sum([]byte{}))
+	return &hashStr
+}
+
+// MatchesHash reports whether hash was calculated from this ParsedMigration.
+// The algorithm is selected by the prefix of hash, so it understands hashes
+// produced by Hash, NormalizedHash and LegacyHash.
+func (m *ParsedMigration) MatchesHash(hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, HashPrefixV2):
+		return *m.Hash() == hash
+	case strings.HasPrefix(hash, HashPrefixV2Normalized):
+		return *m.NormalizedHash() == hash
+	default:
+		return *m.LegacyHash() == hash
+	}
+}
+
+func (m *ParsedMigration) hashV2(prefix string, normalize func(stmt string) string) *string {
+	h := sha256.New()
+	writeLengthPrefixed(h, strconv.FormatBool(m.UseTx))
+	for _, stmt := range m.Stmts {
+		writeLengthPrefixed(h, normalize(stmt))
+	}
+	hashStr := prefix + hex.EncodeToString(h.Sum([]byte{}))
+	return &hashStr
+}
+
+func writeLengthPrefixed(h hash.Hash, s string) {
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(s)))
+	// hash.Write never returns an error as to it's documentation
+	_, _ = h.Write(l[:])
+	_, _ = h.Write([]byte(s))
+}
+
+func normalizeStatement(stmt string) string {
+	lines := strings.Split(stmt, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(strings.Fields(strings.Join(kept, "\n")), " ")
+}
@@ -0,0 +1,53 @@
+package adapt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsedMigration_Hash(t *testing.T) {
+	ab := &ParsedMigration{UseTx: true, Stmts: []string{"ab", "c"}}
+	bc := &ParsedMigration{UseTx: true, Stmts: []string{"a", "bc"}}
+
+	if *ab.LegacyHash() != *bc.LegacyHash() {
+		t.Errorf("LegacyHash() expected ambiguous legacy hashes to be equal")
+	}
+	if *ab.Hash() == *bc.Hash() {
+		t.Errorf("Hash() must differ for different statement boundaries")
+	}
+	if !strings.HasPrefix(*ab.Hash(), HashPrefixV2) {
+		t.Errorf("Hash() = %v, want prefix %v", *ab.Hash(), HashPrefixV2)
+	}
+	if !strings.HasPrefix(*ab.NormalizedHash(), HashPrefixV2Normalized) {
+		t.Errorf("NormalizedHash() = %v, want prefix %v", *ab.NormalizedHash(), HashPrefixV2Normalized)
+	}
+}
+
+func TestParsedMigration_MatchesHash(t *testing.T) {
+	original := &ParsedMigration{UseTx: true, Stmts: []string{"CREATE TABLE a (\n    id INT\n);"}}
+	reformatted := &ParsedMigration{UseTx: true, Stmts: []string{"-- the a table\nCREATE TABLE a ( id INT );"}}
+	changed := &ParsedMigration{UseTx: true, Stmts: []string{"CREATE TABLE a (id BIGINT);"}}
+
+	tests := []struct {
+		name string
+		m    *ParsedMigration
+		hash string
+		want bool
+	}{
+		{"v2", original, *original.Hash(), true},
+		{"legacy", original, *original.LegacyHash(), true},
+		{"normalized", original, *original.NormalizedHash(), true},
+		{"v2 reformatted", reformatted, *original.Hash(), false},
+		{"legacy reformatted", reformatted, *original.LegacyHash(), false},
+		{"normalized reformatted", reformatted, *original.NormalizedHash(), true},
+		{"normalized changed", changed, *original.NormalizedHash(), false},
+		{"tx changed", &ParsedMigration{UseTx: false, Stmts: original.Stmts}, *original.Hash(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.MatchesHash(tt.hash); got != tt.want {
+				t.Errorf("MatchesHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package adapt
 
-import "database/sql"
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+)
 
 // Hook provides callback functions for a HookSource migration. Either MigrateUp,
 // MigrateUpDB or MigrateUpTx must be used. When the Driver executing the
@@ -24,4 +28,43 @@ type Hook struct {
 	// Down-Element of the Hook's associated Migration inside the Driver's
 	// meta-storage
 	MigrateDown func() *ParsedMigration
+	// Fingerprint can optionally identify the content of this Hook, e.g. a
+	// version string that is changed whenever the Hook's code changes. It is
+	// hashed and stored as the Migration's Hash, so that changes to already
+	// applied Hooks are detected by the hash integrity checks.
+	Fingerprint string
+	// Source can optionally contain the Go source code of this Hook, e.g.
+	// embedded using go:embed. It is hashed together with Fingerprint.
+	Source []byte
+	// Replaces lists the IDs of migrations that were squashed into this Hook
+	// (see Squash).
+	Replaces []string
+	// DependsOn lists the IDs of migrations that must be applied before this
+	// Hook.
+	DependsOn []string
+	// Tags lists labels used to filter migrations (see IncludeTags and
+	// ExcludeTags).
+	Tags []string
+	// Repeatable marks the Hook to be applied again whenever its Hash changes
+	// (see RepeatablePrefix). Repeatable Hooks therefore need a Fingerprint or
+	// Source.
+	Repeatable bool
+}
+
+// HashPrefixHookV1 is the prefix of hashes calculated by Hook.Hash
+const HashPrefixHookV1 = "hookv1:"
+
+// Hash calculates a unique hash for the Hook from its Fingerprint and Source.
+// When neither is set nil is returned, because the Hook's content cannot be
+// identified.
+func (h Hook) Hash() *string {
+	if len(h.Fingerprint) == 0 && len(h.Source) == 0 {
+		return nil
+	}
+
+	hash := sha256.New()
+	writeLengthPrefixed(hash, h.Fingerprint)
+	writeLengthPrefixed(hash, string(h.Source))
+	hashStr := HashPrefixHookV1 + hex.EncodeToString(hash.Sum([]byte{}))
+	return &hashStr
 }
@@ -0,0 +1,75 @@
+package adapt
+
+import "time"
+
+// JournalEntry is a record of a maintenance action (like a repair) that
+// changed the meta-storage of a Driver outside the regular migration flow.
+type JournalEntry struct {
+	// Kind identifies the performed action, e.g. JournalRepairHash
+	Kind string
+	// MigrationID is the ID of the Migration the action was performed on
+	MigrationID string
+	// Executor is the name of the program or person that performed the action
+	Executor string
+	// Created is the time the action was performed
+	Created time.Time
+	// Reason describes why the action was performed
+	Reason string
+	// Detail contains additional information, like the previous and new hash
+	Detail string
+}
+
+const (
+	// JournalRepairHash records that the stored hash of a migration was
+	// replaced with the local one
+	JournalRepairHash = "repair_hash"
+	// JournalRepairFinished records that an unfinished migration was marked
+	// as finished
+	JournalRepairFinished = "repair_mark_finished"
+	// JournalRepairDeleted records that an unfinished migration was deleted
+	JournalRepairDeleted = "repair_delete_unfinished"
+	// JournalRepeatableRun records that a repeatable migration was applied
+	JournalRepeatableRun = "repeatable_run"
+	// JournalSchemaSnapshot records the hash of the schema after migrating
+	// (see RecordSchemaSnapshot)
+	JournalSchemaSnapshot = "schema_snapshot"
+)
+
+// DriverJournal is an optional extension of Driver. It stores JournalEntry
+// records next to the applied migrations, so that changes to the meta-storage
+// outside the regular migration flow stay traceable.
+type DriverJournal interface {
+	Driver
+	// AddJournalEntry must persist the passed JournalEntry.
+	AddJournalEntry(entry *JournalEntry) error
+	// ListJournalEntries must list all persisted JournalEntry records ordered
+	// by their Created time.
+	ListJournalEntries() ([]*JournalEntry, error)
+}
+
+// journalSupporter is implemented by driver adapters, like the one returned by
+// FromSqlStatementsDriver, that always provide the methods of DriverJournal but
+// can only perform them when the wrapped driver supports it.
+type journalSupporter interface {
+	supportsJournal() bool
+}
+
+// asJournal returns driver as DriverJournal, if it implements it and actually
+// supports a journal.
+func asJournal(driver Driver) (DriverJournal, bool) {
+	journal, ok := driver.(DriverJournal)
+	if s, isAdapter := driver.(journalSupporter); ok && isAdapter {
+		ok = s.supportsJournal()
+	}
+	return journal, ok
+}
+
+// DriverMigrationRemover is an optional extension of Driver. It allows adapt
+// to remove the meta-data of a migration without running a Down migration,
+// e.g. when repairing a migration that started but never finished.
+type DriverMigrationRemover interface {
+	Driver
+	// RemoveMigration must delete the meta-data of the migration with
+	// migrationID.
+	RemoveMigration(migrationID string) error
+}
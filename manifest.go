@@ -0,0 +1,160 @@
+package adapt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ErrInvalidSignature is returned when the ed25519 signature of a Manifest
+// cannot be verified with the configured public key.
+var ErrInvalidSignature = errors.New("adapt: invalid manifest signature")
+
+// Manifest pins the expected content of migrations by listing the hashes of
+// the Up and Down part of every migration (see ParsedMigration.Hash). It is
+// used by sources like NewArchiveSource to verify that the provided migrations
+// are exactly the ones that were approved. A Manifest is stored as JSON:
+//
+//	{
+//	  "migrations": [
+//	    {"id": "20240101_1200_init", "hash": "...", "down_hash": "..."}
+//	  ]
+//	}
+type Manifest struct {
+	Migrations []*ManifestEntry `json:"migrations"`
+}
+
+// ManifestEntry is a single migration pinned by a Manifest
+type ManifestEntry struct {
+	// ID is the unique identifier of the migration
+	ID string `json:"id"`
+	// Hash is the expected hash of the migration's parsed Up part
+	Hash string `json:"hash"`
+	// DownHash is the expected hash of the migration's parsed Down part. It
+	// must be set when the migration has a Down part and empty otherwise.
+	DownHash string `json:"down_hash,omitempty"`
+	// Files optionally lists the file names that make up this migration. It
+	// is used by sources that need to know which files to fetch.
+	Files []string `json:"files,omitempty"`
+}
+
+// ParseManifest decodes a JSON encoded Manifest and checks that it doesn't
+// contain duplicated or empty IDs.
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("adapt/ParseManifest: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(m.Migrations))
+	for _, entry := range m.Migrations {
+		if entry == nil || len(entry.ID) == 0 {
+			return nil, fmt.Errorf("adapt/ParseManifest: entry without id")
+		}
+		if _, ok := seen[entry.ID]; ok {
+			return nil, fmt.Errorf("adapt/ParseManifest: duplicated id %q", entry.ID)
+		}
+		seen[entry.ID] = struct{}{}
+	}
+
+	return m, nil
+}
+
+// SignManifest signs the raw JSON encoded Manifest data with the ed25519
+// private key and returns the base64 encoded signature, as expected by
+// VerifyManifest.
+func SignManifest(key ed25519.PrivateKey, data []byte) []byte {
+	sig := ed25519.Sign(key, data)
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(sig)))
+	base64.StdEncoding.Encode(buf, sig)
+	return buf
+}
+
+// VerifyManifest verifies the base64 encoded ed25519 signature over the raw
+// JSON encoded Manifest data and parses it afterwards. When the signature is
+// invalid ErrInvalidSignature is returned.
+func VerifyManifest(key ed25519.PublicKey, data []byte, signature []byte) (*Manifest, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("adapt/VerifyManifest: invalid public key size %d", len(key))
+	}
+
+	sig := make([]byte, base64.StdEncoding.DecodedLen(len(signature)))
+	n, err := base64.StdEncoding.Decode(sig, bytes.TrimSpace(signature))
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if !ed25519.Verify(key, data, sig[:n]) {
+		return nil, ErrInvalidSignature
+	}
+
+	return ParseManifest(data)
+}
+
+// Entry returns the ManifestEntry for id or nil, if the Manifest doesn't
+// contain this id.
+func (m *Manifest) Entry(id string) *ManifestEntry {
+	for _, entry := range m.Migrations {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// verifySource checks that the Up and Down part of every migration of src is
+// pinned by the Manifest with an equal hash and that every pinned migration is
+// provided by src.
+func (m *Manifest) verifySource(src SqlStatementsSource, log *slog.Logger) error {
+	ids, err := src.ListMigrations()
+	if err != nil {
+		return err
+	}
+
+	listed := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		listed[id] = struct{}{}
+
+		entry := m.Entry(id)
+		if entry == nil {
+			log.Error("migration isn't pinned by manifest", "migration_id", id)
+			return ErrIntegrityProtection
+		}
+
+		parsed, err := src.GetParsedUpMigration(id)
+		if err != nil {
+			return err
+		}
+		if hash := parsed.Hash(); !parsed.MatchesHash(entry.Hash) {
+			log.Error("hash of migration doesn't match manifest",
+				"migration_id", id, "local_hash", *hash, "manifest_hash", entry.Hash)
+			return ErrIntegrityProtection
+		}
+
+		down, err := src.GetParsedDownMigration(id)
+		if err != nil {
+			return err
+		}
+		switch {
+		case down == nil && len(entry.DownHash) > 0:
+			log.Error("down migration pinned by manifest is missing", "migration_id", id)
+			return ErrIntegrityProtection
+		case down != nil && !down.MatchesHash(entry.DownHash):
+			log.Error("hash of down migration doesn't match manifest",
+				"migration_id", id, "local_hash", *down.Hash(), "manifest_hash", entry.DownHash)
+			return ErrIntegrityProtection
+		}
+	}
+
+	for _, entry := range m.Migrations {
+		if _, ok := listed[entry.ID]; !ok {
+			log.Error("migration pinned by manifest is missing", "migration_id", entry.ID)
+			return ErrIntegrityProtection
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package adapt
+
+import (
+	"time"
+)
+
+// MetricLabels identify the origin of a recorded metric
+type MetricLabels struct {
+	// Driver is the name of the Driver (see Driver.Name)
+	Driver string
+	// Executor is the executor passed to Migrate or the name of the operation
+	// (e.g. "adapt/rollback")
+	Executor string
+}
+
+// Outcome reports whether a recorded migration or statement succeeded
+type Outcome string
+
+const (
+	// OutcomeSuccess is recorded for successful migrations and statements
+	OutcomeSuccess Outcome = "success"
+	// OutcomeFailure is recorded for failed migrations and statements
+	OutcomeFailure Outcome = "failure"
+)
+
+// Metrics records measurements of adapt operations. Use CollectMetrics to pass
+// an implementation to an operation. PrometheusMetrics implements Metrics
+// without any dependencies.
+type Metrics interface {
+	// ObserveMigration records the duration of an applied migration
+	ObserveMigration(labels MetricLabels, outcome Outcome, duration time.Duration)
+	// ObserveStatement records the duration of an executed statement
+	ObserveStatement(labels MetricLabels, outcome Outcome, duration time.Duration)
+	// ObserveLockWait records the time it took to acquire the Driver's lock,
+	// including failed attempts
+	ObserveLockWait(labels MetricLabels, wait time.Duration)
+	// IncFailures is called once for every failed operation. stage is the
+	// Stage that failed, or empty when the operation failed outside a Stage.
+	IncFailures(labels MetricLabels, stage Stage)
+	// SetPendingMigrations reports the number of migrations that are pending
+	// (see StatusPending) for the Driver. It is called after the applied
+	// migrations are loaded and again at the end of the operation.
+	SetPendingMigrations(driver string, pending int)
+}
@@ -0,0 +1,253 @@
+package adapt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPrometheusBuckets are the upper bounds (in seconds) of the histogram
+// buckets used by NewPrometheusMetrics when no buckets are provided.
+var DefaultPrometheusBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300}
+
+/*
+PrometheusMetrics implements Metrics by keeping all values in memory and
+exposing them in the Prometheus text exposition format. It doesn't depend on
+the Prometheus client library and can be shared by multiple operations. The
+following metrics are exposed:
+
+	adapt_migration_duration_seconds   histogram {driver, executor, outcome}
+	adapt_statement_duration_seconds   histogram {driver, executor, outcome}
+	adapt_lock_wait_seconds            histogram {driver, executor}
+	adapt_failures_total               counter   {driver, executor, stage}
+	adapt_pending_migrations           gauge     {driver}
+
+PrometheusMetrics is a http.Handler and can therefore be served directly:
+
+	metrics := adapt.NewPrometheusMetrics()
+	http.Handle("/metrics", metrics)
+
+	err := adapt.Migrate("my-service", driver, sources, adapt.CollectMetrics(metrics))
+
+Short-lived processes can use WriteTo to write a file for the textfile
+collector of the Prometheus node exporter instead.
+*/
+type PrometheusMetrics struct {
+	mu         sync.Mutex
+	buckets    []float64
+	migrations *promFamily
+	statements *promFamily
+	lockWait   *promFamily
+	failures   *promFamily
+	pending    *promFamily
+}
+
+// NewPrometheusMetrics returns an empty PrometheusMetrics. buckets are the
+// upper bounds (in seconds) of the histogram buckets. When no buckets are
+// provided DefaultPrometheusBuckets is used.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultPrometheusBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	return &PrometheusMetrics{
+		buckets:    sorted,
+		migrations: newPromFamily("adapt_migration_duration_seconds", "Duration of applied migrations.", promHistogram, "driver", "executor", "outcome"),
+		statements: newPromFamily("adapt_statement_duration_seconds", "Duration of executed migration statements.", promHistogram, "driver", "executor", "outcome"),
+		lockWait:   newPromFamily("adapt_lock_wait_seconds", "Time spent waiting for the driver lock.", promHistogram, "driver", "executor"),
+		failures:   newPromFamily("adapt_failures_total", "Number of failed operations.", promCounter, "driver", "executor", "stage"),
+		pending:    newPromFamily("adapt_pending_migrations", "Number of migrations that are not applied yet.", promGauge, "driver"),
+	}
+}
+
+func (p *PrometheusMetrics) ObserveMigration(labels MetricLabels, outcome Outcome, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.migrations.get(p.buckets, labels.Driver, labels.Executor, string(outcome)).observe(duration.Seconds())
+}
+
+func (p *PrometheusMetrics) ObserveStatement(labels MetricLabels, outcome Outcome, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.statements.get(p.buckets, labels.Driver, labels.Executor, string(outcome)).observe(duration.Seconds())
+}
+
+func (p *PrometheusMetrics) ObserveLockWait(labels MetricLabels, wait time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lockWait.get(p.buckets, labels.Driver, labels.Executor).observe(wait.Seconds())
+}
+
+func (p *PrometheusMetrics) IncFailures(labels MetricLabels, stage Stage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failures.get(p.buckets, labels.Driver, labels.Executor, string(stage)).value++
+}
+
+func (p *PrometheusMetrics) SetPendingMigrations(driver string, pending int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending.get(p.buckets, driver).value = float64(pending)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format to w
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	var buf bytes.Buffer
+	for _, f := range []*promFamily{p.migrations, p.statements, p.lockWait, p.failures, p.pending} {
+		f.write(&buf)
+	}
+	p.mu.Unlock()
+
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP responds with all metrics in the Prometheus text exposition format
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+type promType string
+
+const (
+	promCounter   promType = "counter"
+	promGauge     promType = "gauge"
+	promHistogram promType = "histogram"
+)
+
+type promFamily struct {
+	name   string
+	help   string
+	typ    promType
+	labels []string
+	series map[string]*promSeries
+}
+
+type promSeries struct {
+	values []string
+	// value of a counter or gauge
+	value float64
+	// counts (per bucket, not cumulative), sum and count of a histogram
+	counts []uint64
+	sum    float64
+	count  uint64
+	bounds []float64
+}
+
+func newPromFamily(name string, help string, typ promType, labels ...string) *promFamily {
+	return &promFamily{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		series: make(map[string]*promSeries),
+	}
+}
+
+func (f *promFamily) get(buckets []float64, values ...string) *promSeries {
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &promSeries{values: values}
+		if f.typ == promHistogram {
+			s.bounds = buckets
+			s.counts = make([]uint64, len(buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (s *promSeries) observe(v float64) {
+	for i, bound := range s.bounds {
+		if v <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (f *promFamily) write(w *bytes.Buffer) {
+	if len(f.series) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		// formatLabels leaves capacity for the additional "le" label, so it
+		// can be appended without copying
+		labels := f.formatLabels(s.values)
+
+		if f.typ != promHistogram {
+			_, _ = fmt.Fprintf(w, "%s%s %s\n", f.name, wrapLabels(labels), formatPromFloat(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range s.bounds {
+			cumulative += s.counts[i]
+			_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, wrapLabels(append(labels, promLabel("le", formatPromFloat(bound)))), cumulative)
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, wrapLabels(append(labels, promLabel("le", "+Inf"))), s.count)
+		_, _ = fmt.Fprintf(w, "%s_sum%s %s\n", f.name, wrapLabels(labels), formatPromFloat(s.sum))
+		_, _ = fmt.Fprintf(w, "%s_count%s %d\n", f.name, wrapLabels(labels), s.count)
+	}
+}
+
+func (f *promFamily) formatLabels(values []string) []string {
+	labels := make([]string, 0, len(f.labels)+1)
+	for i, name := range f.labels {
+		labels = append(labels, promLabel(name, values[i]))
+	}
+	return labels
+}
+
+func wrapLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabel(name string, value string) string {
+	return name + `="` + promLabelEscaper.Replace(value) + `"`
+}
+
+func formatPromFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
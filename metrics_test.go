@@ -0,0 +1,76 @@
+package adapt
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	p := NewPrometheusMetrics(1, 0.1)
+	labels := MetricLabels{Driver: "driver_postgres", Executor: `svc "a"`}
+
+	p.ObserveMigration(labels, OutcomeSuccess, 50*time.Millisecond)
+	p.ObserveMigration(labels, OutcomeSuccess, 500*time.Millisecond)
+	p.ObserveMigration(labels, OutcomeFailure, 2*time.Second)
+	p.ObserveLockWait(labels, 0)
+	p.IncFailures(labels, StageMigrate)
+	p.IncFailures(labels, StageMigrate)
+	p.SetPendingMigrations("driver_postgres", 3)
+	p.SetPendingMigrations("driver_postgres", 1)
+
+	want := `# HELP adapt_migration_duration_seconds Duration of applied migrations.
+# TYPE adapt_migration_duration_seconds histogram
+adapt_migration_duration_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",outcome="failure",le="0.1"} 0
+adapt_migration_duration_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",outcome="failure",le="1"} 0
+adapt_migration_duration_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",outcome="failure",le="+Inf"} 1
+adapt_migration_duration_seconds_sum{driver="driver_postgres",executor="svc \"a\"",outcome="failure"} 2
+adapt_migration_duration_seconds_count{driver="driver_postgres",executor="svc \"a\"",outcome="failure"} 1
+adapt_migration_duration_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",outcome="success",le="0.1"} 1
+adapt_migration_duration_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",outcome="success",le="1"} 2
+adapt_migration_duration_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",outcome="success",le="+Inf"} 2
+adapt_migration_duration_seconds_sum{driver="driver_postgres",executor="svc \"a\"",outcome="success"} 0.55
+adapt_migration_duration_seconds_count{driver="driver_postgres",executor="svc \"a\"",outcome="success"} 2
+# HELP adapt_lock_wait_seconds Time spent waiting for the driver lock.
+# TYPE adapt_lock_wait_seconds histogram
+adapt_lock_wait_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",le="0.1"} 1
+adapt_lock_wait_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",le="1"} 1
+adapt_lock_wait_seconds_bucket{driver="driver_postgres",executor="svc \"a\"",le="+Inf"} 1
+adapt_lock_wait_seconds_sum{driver="driver_postgres",executor="svc \"a\""} 0
+adapt_lock_wait_seconds_count{driver="driver_postgres",executor="svc \"a\""} 1
+# HELP adapt_failures_total Number of failed operations.
+# TYPE adapt_failures_total counter
+adapt_failures_total{driver="driver_postgres",executor="svc \"a\"",stage="migrate"} 2
+# HELP adapt_pending_migrations Number of migrations that are not applied yet.
+# TYPE adapt_pending_migrations gauge
+adapt_pending_migrations{driver="driver_postgres"} 1
+`
+
+	var buf bytes.Buffer
+	if _, err := p.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", got, want)
+	}
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if got := rec.Body.String(); got != want {
+		t.Errorf("ServeHTTP() body =\n%s\nwant\n%s", got, want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("ServeHTTP() Content-Type = %q", got)
+	}
+}
+
+func TestPrometheusMetrics_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewPrometheusMetrics().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("WriteTo() = %q, want empty output", buf.String())
+	}
+}
@@ -2,6 +2,7 @@ package adapt
 
 import (
 	"log/slog"
+	"strings"
 	"time"
 )
 
@@ -47,14 +48,33 @@ type AvailableMigration struct {
 	// ParsedUp is a ParsedMigration set by Enrich if the Source is a
 	// SqlStatementsSource
 	ParsedUp *ParsedMigration
-	// Hash is the unique migration hash from ParsedMigration.Hash set by Enrich
-	// if the Source is a SqlStatementsSource
+	// Replaces lists the IDs of migrations that were squashed into this
+	// AvailableMigration. It is set by Enrich from ParsedMigration.Replaces or
+	// Hook.Replaces
+	Replaces []string
+	// DependsOn lists the IDs of migrations that must be applied before this
+	// AvailableMigration. It is set by Enrich from ParsedMigration.DependsOn or
+	// Hook.DependsOn
+	DependsOn []string
+	// Tags lists labels used to filter migrations. It is set by Enrich from
+	// ParsedMigration.Tags or Hook.Tags
+	Tags []string
+	// Repeatable is set by Enrich when the ID starts with RepeatablePrefix or
+	// the migration was marked using ParsedMigration.Repeatable or
+	// Hook.Repeatable
+	Repeatable bool
+	// Hash is the unique migration hash set by Enrich. It is calculated with
+	// ParsedMigration.Hash if the Source is a SqlStatementsSource or with
+	// Hook.Hash if the Source is a HookSource
 	Hash *string
 }
 
 // Enrich checks the type of Source and adds further information to the
-// AvailableMigration, like ParsedUp and Hash for SqlStatementsSource
+// AvailableMigration, like ParsedUp and Hash for SqlStatementsSource or Hash
+// for HookSource
 func (m *AvailableMigration) Enrich(log *slog.Logger) error {
+	m.Repeatable = strings.HasPrefix(m.ID, RepeatablePrefix)
+
 	switch src := m.Source.(type) {
 	case SqlStatementsSource:
 		// parse migration from Source
@@ -66,6 +86,17 @@ func (m *AvailableMigration) Enrich(log *slog.Logger) error {
 
 		m.ParsedUp = parsed
 		m.Hash = parsed.Hash()
+		m.Replaces = parsed.Replaces
+		m.DependsOn = parsed.DependsOn
+		m.Tags = parsed.Tags
+		m.Repeatable = m.Repeatable || parsed.Repeatable
+	case HookSource:
+		hook := src.GetHook(m.ID)
+		m.Hash = hook.Hash()
+		m.Replaces = hook.Replaces
+		m.DependsOn = hook.DependsOn
+		m.Tags = hook.Tags
+		m.Repeatable = m.Repeatable || hook.Repeatable
 	}
 	return nil
 }
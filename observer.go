@@ -0,0 +1,140 @@
+package adapt
+
+import (
+	"time"
+)
+
+// Stage identifies a single step of an adapt operation. Every operation that
+// uses a Driver runs StageInit, StageHealthCheck, StagePrepareLocal,
+// StageLock, StagePrepareRemote and StageClose. Migrate additionally runs StageMigrate
+// (after StageRollback when unknown migrations are reverted) and Rollback runs
+// StageRollback. Repair runs StageRepair instead of StagePrepareRemote.
+type Stage string
+
+const (
+	// StageInit initializes the Driver and all sources
+	StageInit Stage = "init"
+	// StageHealthCheck checks the health of the Driver
+	StageHealthCheck Stage = "health_check"
+	// StagePrepareLocal collects all available migrations from the sources
+	StagePrepareLocal Stage = "prepare_local"
+	// StageLock acquires the lock of the Driver, unless locks are disabled
+	// or unsupported
+	StageLock Stage = "lock"
+	// StagePrepareRemote loads all applied migrations from the Driver
+	StagePrepareRemote Stage = "prepare_remote"
+	// StageMigrate applies all needed migrations
+	StageMigrate Stage = "migrate"
+	// StageRollback reverts applied migrations using their stored down
+	// migrations
+	StageRollback Stage = "rollback"
+	// StageRepair repairs the meta-storage of the Driver (see Repair)
+	StageRepair Stage = "repair"
+	// StageClose closes the Driver
+	StageClose Stage = "close"
+)
+
+// StageEvent is passed to Observer.OnStageStart and Observer.OnStageEnd
+type StageEvent struct {
+	Stage    Stage
+	Executor string
+	Driver   string
+	// Duration is only set for Observer.OnStageEnd
+	Duration time.Duration
+	// Err is only set for Observer.OnStageEnd and reports why the stage failed
+	Err error
+}
+
+// MigrationEvent is passed to Observer.OnMigrationStart and
+// Observer.OnMigrationEnd
+type MigrationEvent struct {
+	MigrationID     string
+	Executor        string
+	Driver          string
+	Deployment      string
+	DeploymentOrder int
+	Repeatable      bool
+	// Duration is only set for Observer.OnMigrationEnd
+	Duration time.Duration
+	// Err is only set for Observer.OnMigrationEnd and reports why the
+	// migration failed
+	Err error
+}
+
+// StatementEvent is passed to Observer.OnStatement after a statement of a
+// SqlStatementsSource migration was executed, including statements of down
+// migrations executed during a rollback.
+type StatementEvent struct {
+	MigrationID string
+	Executor    string
+	Driver      string
+	Statement   string
+	// Index is the position of Statement within its migration
+	Index int
+	// InTx reports whether Statement was executed inside a transaction
+	InTx     bool
+	Duration time.Duration
+	Err      error
+}
+
+// RollbackEvent is passed to Observer.OnRollback after a migration was
+// reverted using its stored down migration
+type RollbackEvent struct {
+	MigrationID string
+	Executor    string
+	Driver      string
+	Duration    time.Duration
+	Err         error
+}
+
+// LockEvent is passed to Observer.OnLockAcquired and Observer.OnLockReleased
+type LockEvent struct {
+	Executor string
+	Driver   string
+	// Wait is the time it took to acquire the lock, or to fail doing so. It is
+	// only set for Observer.OnLockAcquired.
+	Wait time.Duration
+	// Held is the time the lock was held. It is only set for
+	// Observer.OnLockReleased.
+	Held time.Duration
+	// Err is set for Observer.OnLockAcquired when the lock couldn't be
+	// acquired
+	Err error
+}
+
+// Observer receives lifecycle events of adapt operations. It can be used to
+// drive progress bars, send notifications or write audit trails without
+// parsing log output. Observer methods are called synchronously, so they
+// should return quickly. Embed NopObserver to only implement some of them.
+type Observer interface {
+	// OnStageStart is called before a Stage runs
+	OnStageStart(event StageEvent)
+	// OnStageEnd is called after a Stage finished, successful or not
+	OnStageEnd(event StageEvent)
+	// OnMigrationStart is called before a migration is applied
+	OnMigrationStart(event MigrationEvent)
+	// OnMigrationEnd is called after a migration was applied, successful or
+	// not
+	OnMigrationEnd(event MigrationEvent)
+	// OnStatement is called after every executed statement
+	OnStatement(event StatementEvent)
+	// OnRollback is called after a migration was reverted, successful or not
+	OnRollback(event RollbackEvent)
+	// OnLockAcquired is called after the Driver tried to acquire its lock,
+	// successful or not
+	OnLockAcquired(event LockEvent)
+	// OnLockReleased is called after the Driver released its lock
+	OnLockReleased(event LockEvent)
+}
+
+// NopObserver implements Observer by ignoring all events
+type NopObserver struct{}
+
+func (NopObserver) OnStageStart(StageEvent)         {}
+func (NopObserver) OnStageEnd(StageEvent)           {}
+func (NopObserver) OnMigrationStart(MigrationEvent) {}
+func (NopObserver) OnMigrationEnd(MigrationEvent)   {}
+func (NopObserver) OnStatement(StatementEvent)      {}
+func (NopObserver) OnRollback(RollbackEvent)        {}
+func (NopObserver) OnLockAcquired(LockEvent)        {}
+func (NopObserver) OnLockReleased(LockEvent)        {}
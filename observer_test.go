@@ -0,0 +1,310 @@
+package adapt_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/harwoeck/adapt"
+	"github.com/harwoeck/adapt/adapttest"
+	"github.com/harwoeck/adapt/adapttest/fakesql"
+)
+
+// traceObserver records all events as short strings
+type traceObserver struct {
+	trace []string
+}
+
+func (o *traceObserver) add(format string, args ...any) {
+	o.trace = append(o.trace, fmt.Sprintf(format, args...))
+}
+
+func (o *traceObserver) OnStageStart(e adapt.StageEvent) {
+	o.add("stage start %s", e.Stage)
+}
+
+func (o *traceObserver) OnStageEnd(e adapt.StageEvent) {
+	o.add("stage end %s err=%v", e.Stage, e.Err != nil)
+}
+
+func (o *traceObserver) OnMigrationStart(e adapt.MigrationEvent) {
+	o.add("migration start %s", e.MigrationID)
+}
+
+func (o *traceObserver) OnMigrationEnd(e adapt.MigrationEvent) {
+	o.add("migration end %s err=%v", e.MigrationID, e.Err != nil)
+}
+
+func (o *traceObserver) OnStatement(e adapt.StatementEvent) {
+	o.add("statement %s #%d tx=%v err=%v", e.MigrationID, e.Index, e.InTx, e.Err != nil)
+}
+
+func (o *traceObserver) OnRollback(e adapt.RollbackEvent) {
+	o.add("rollback %s err=%v", e.MigrationID, e.Err != nil)
+}
+
+func (o *traceObserver) OnLockAcquired(e adapt.LockEvent) {
+	o.add("lock acquired err=%v", e.Err != nil)
+}
+
+func (o *traceObserver) OnLockReleased(adapt.LockEvent) {
+	o.add("lock released")
+}
+
+// lockingDriver is a RecordingDriver that supports locks
+type lockingDriver struct {
+	*adapttest.RecordingDriver
+	lockErr error
+}
+
+func (d *lockingDriver) SupportsLocks() bool {
+	return true
+}
+
+func (d *lockingDriver) AcquireLock() error {
+	return d.lockErr
+}
+
+func (d *lockingDriver) ReleaseLock() error {
+	return nil
+}
+
+func TestObserve(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    func() adapt.DatabaseDriver
+		failAt    int
+		wantTrace []string
+		wantErr   bool
+	}{
+		{
+			name: "migrate",
+			driver: func() adapt.DatabaseDriver {
+				return &lockingDriver{RecordingDriver: adapttest.NewRecordingDriver()}
+			},
+			wantTrace: []string{
+				"stage start init",
+				"stage end init err=false",
+				"stage start health_check",
+				"stage end health_check err=false",
+				"stage start prepare_local",
+				"stage end prepare_local err=false",
+				"stage start lock",
+				"lock acquired err=false",
+				"stage end lock err=false",
+				"stage start prepare_remote",
+				"stage end prepare_remote err=false",
+				"stage start migrate",
+				"migration start 1_a",
+				"statement 1_a #0 tx=true err=false",
+				"statement 1_a #1 tx=true err=false",
+				"migration end 1_a err=false",
+				"migration start 2_b",
+				"statement 2_b #0 tx=true err=false",
+				"migration end 2_b err=false",
+				"stage end migrate err=false",
+				"lock released",
+				"stage start close",
+				"stage end close err=false",
+			},
+		},
+		{
+			name: "failing lock",
+			driver: func() adapt.DatabaseDriver {
+				return &lockingDriver{RecordingDriver: adapttest.NewRecordingDriver(), lockErr: errors.New("locked")}
+			},
+			wantTrace: []string{
+				"stage start init",
+				"stage end init err=false",
+				"stage start health_check",
+				"stage end health_check err=false",
+				"stage start prepare_local",
+				"stage end prepare_local err=false",
+				"stage start lock",
+				"lock acquired err=true",
+				"stage end lock err=true",
+				"stage start close",
+				"stage end close err=false",
+			},
+			wantErr: true,
+		},
+		{
+			name: "failing statement",
+			driver: func() adapt.DatabaseDriver {
+				return adapttest.NewRecordingDriver()
+			},
+			failAt: 2,
+			wantTrace: []string{
+				"stage start init",
+				"stage end init err=false",
+				"stage start health_check",
+				"stage end health_check err=false",
+				"stage start prepare_local",
+				"stage end prepare_local err=false",
+				"stage start lock",
+				"stage end lock err=false",
+				"stage start prepare_remote",
+				"stage end prepare_remote err=false",
+				"stage start migrate",
+				"migration start 1_a",
+				"statement 1_a #0 tx=true err=false",
+				"statement 1_a #1 tx=true err=true",
+				"migration end 1_a err=true",
+				"stage end migrate err=true",
+				"stage start close",
+				"stage end close err=false",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := tt.driver()
+			if tt.failAt > 0 {
+				driver.(*adapttest.RecordingDriver).Recorder().FailStatement(tt.failAt, errors.New("injected"))
+			}
+
+			o := &traceObserver{}
+			err := adapt.Migrate("test", driver, adapt.SourceCollection{
+				adapt.NewMemoryFSSource(map[string]string{
+					"1_a.up.sql": "CREATE TABLE a (id INT);\nCREATE INDEX a_id ON a (id);",
+					"2_b.up.sql": "CREATE TABLE b (id INT);",
+				}),
+			}, adapt.DisableLogger(), adapt.Observe(o))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(o.trace, tt.wantTrace) {
+				t.Errorf("Migrate() trace = %q, want %q", o.trace, tt.wantTrace)
+			}
+		})
+	}
+}
+
+func TestObserve_Rollback(t *testing.T) {
+	driver := adapttest.NewRecordingDriver()
+	sources := adapt.SourceCollection{
+		adapt.NewMemoryFSSource(map[string]string{
+			"1_a.sql": "-- +adapt Up\nCREATE TABLE a (id INT);\n-- +adapt Down\nDROP TABLE a;",
+		}),
+	}
+
+	err := adapt.Migrate("test", driver, sources, adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	o := &traceObserver{}
+	_, err = adapt.Rollback(driver, sources, 1, adapt.DisableLogger(), adapt.Observe(o))
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	want := []string{
+		"stage start rollback",
+		"statement 1_a #0 tx=true err=false",
+		"rollback 1_a err=false",
+		"stage end rollback err=false",
+	}
+	// skip the events of all stages preparing and closing the operation
+	if got := o.trace[10 : len(o.trace)-2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rollback() trace = %q, want %q", got, want)
+	}
+}
+
+func TestObserve_Repair(t *testing.T) {
+	driver := adapttest.NewRecordingDriver()
+	sources := adapt.SourceCollection{
+		adapt.NewMemoryFSSource(map[string]string{"1_a.up.sql": "CREATE TABLE a (id INT);"}),
+	}
+
+	err := adapt.Migrate("test", driver, sources, adapt.DisableLogger())
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	o := &traceObserver{}
+	m := &traceMetrics{}
+	_, err = adapt.Repair(driver, sources, adapt.RepairBy("jane.doe"), adapt.RepairReason("test"), adapt.RepairHashes(),
+		adapt.RepairWithOptions(adapt.DisableLogger(), adapt.Observe(o), adapt.CollectMetrics(m)))
+	if err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+
+	want := []string{
+		"stage start init",
+		"stage end init err=false",
+		"stage start health_check",
+		"stage end health_check err=false",
+		"stage start prepare_local",
+		"stage end prepare_local err=false",
+		"stage start lock",
+		"stage end lock err=false",
+		"stage start repair",
+		"stage end repair err=false",
+		"stage start close",
+		"stage end close err=false",
+	}
+	if !reflect.DeepEqual(o.trace, want) {
+		t.Errorf("Repair() trace = %q, want %q", o.trace, want)
+	}
+	if len(m.trace) != 0 {
+		t.Errorf("Repair() metrics = %q, want none", m.trace)
+	}
+}
+
+func TestCollectMetrics_RepairFailure(t *testing.T) {
+	m := &traceMetrics{}
+	_, err := adapt.Repair(noJournalDriver{adapttest.NewRecordingDriver()}, nil,
+		adapt.RepairBy("jane.doe"), adapt.RepairReason("test"), adapt.RepairHashes(),
+		adapt.RepairWithOptions(adapt.DisableLogger(), adapt.CollectMetrics(m)))
+	if err == nil {
+		t.Fatalf("Repair() error = nil, want error for driver without journal")
+	}
+
+	want := []string{"failure repair"}
+	if !reflect.DeepEqual(m.trace, want) {
+		t.Errorf("Repair() metrics = %q, want %q", m.trace, want)
+	}
+}
+
+// noJournalDriver hides the journal of a RecordingDriver
+type noJournalDriver struct {
+	adapt.Driver
+}
+
+// TestObserve_SqlStatementsDriver checks that statements executed by the
+// adapter of FromSqlStatementsDriver are reported as well
+func TestObserve_SqlStatementsDriver(t *testing.T) {
+	db, _ := fakesql.Open()
+
+	o := &traceObserver{}
+	err := adapt.Migrate("test", adapt.NewSQLiteDriver(db), adapt.SourceCollection{
+		adapt.NewMemoryFSSource(map[string]string{
+			"1_a.up.sql": "CREATE TABLE a (id INT);\nCREATE INDEX a_id ON a (id);",
+		}),
+	}, adapt.DisableLogger(), adapt.Observe(o))
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	want := []string{
+		"migration start 1_a",
+		"statement 1_a #0 tx=false err=false",
+		"statement 1_a #1 tx=false err=false",
+		"migration end 1_a err=false",
+	}
+	// skip the events of all stages preparing the operation
+	if got := o.trace[11:15]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Migrate() trace = %q, want %q", got, want)
+	}
+}
+
+func TestObserve_Nil(t *testing.T) {
+	err := adapt.Migrate("test", adapttest.NewRecordingDriver(), nil, adapt.DisableLogger(), adapt.Observe(nil))
+	if err == nil {
+		t.Errorf("Migrate() error = nil, want error for nil observer")
+	}
+}
@@ -1,6 +1,8 @@
 package adapt
 
 import (
+	"crypto/ed25519"
+	"fmt"
 	"log/slog"
 )
 
@@ -19,6 +21,84 @@ func DisableHashIntegrityChecks() Option {
 	}
 }
 
+// NormalizedHashes instructs adapt to calculate hashes of SqlStatementsSource
+// migrations using ParsedMigration.NormalizedHash instead of ParsedMigration.Hash.
+// Formatting changes (whitespace and comment lines) to already applied migrations
+// will therefore not be treated as integrity violations. Stored hashes of other
+// algorithms are still understood and upgraded like described in
+// DisableHashUpgrades.
+func NormalizedHashes() Option {
+	return func(e *exec) error {
+		e.optNormalizedHashes = true
+		return nil
+	}
+}
+
+// DisableHashUpgrades disables the transparent upgrade of stored hashes. By
+// default, adapt replaces the stored hash of an applied migration, when it
+// matches the local migration but was produced by a different hash algorithm
+// (e.g. a legacy hash from an older adapt version), as long as the Driver
+// implements DriverHashUpdater.
+func DisableHashUpgrades() Option {
+	return func(e *exec) error {
+		e.optDisableHashUpgrades = true
+		return nil
+	}
+}
+
+// HookChangePolicy specifies how adapt reacts, when the hash of an already
+// applied Hook migration (see Hook.Fingerprint) differs from the local one.
+type HookChangePolicy int
+
+const (
+	// HookChangeError aborts with ErrIntegrityProtection. It is the default
+	// HookChangePolicy and matches the behaviour of SqlStatementsSource
+	// migrations.
+	HookChangeError HookChangePolicy = iota
+	// HookChangeWarn logs a warning and continues.
+	HookChangeWarn
+)
+
+// OnHookChange sets the HookChangePolicy for changed Hook migrations. By default,
+// HookChangeError is used.
+func OnHookChange(policy HookChangePolicy) Option {
+	return func(e *exec) error {
+		e.optHookChangePolicy = policy
+		return nil
+	}
+}
+
+// BaselineOnEmptyMeta records all available migrations up to (and including)
+// id as applied without executing them, when the Driver's meta-storage doesn't
+// contain any applied migrations yet. Afterwards Migrate continues as usual.
+// It allows adopting adapt on existing databases without a separate call to
+// Baseline.
+func BaselineOnEmptyMeta(id string) Option {
+	return func(e *exec) error {
+		e.optBaselineOnEmptyMeta = id
+		return nil
+	}
+}
+
+// RequireSignedManifest verifies the ed25519 signature of the JSON encoded
+// Manifest (see SignManifest and VerifyManifest) and afterwards refuses to apply
+// any migration whose hash is missing or differs from the one pinned in the
+// Manifest. The Down part of a migration must match ManifestEntry.DownHash, as
+// it is stored with the migration and executed by rollbacks. This guarantees
+// that only migrations approved by review are applied, while the hash integrity
+// checks only protect already applied migrations.
+func RequireSignedManifest(key ed25519.PublicKey, manifest []byte, signature []byte) Option {
+	return func(e *exec) error {
+		m, err := VerifyManifest(key, manifest, signature)
+		if err != nil {
+			return err
+		}
+
+		e.optManifest = m
+		return nil
+	}
+}
+
 // DisableDriverLocks disables mutex acquiring/releasing of a Driver, even if the Driver
 // itself reports to support locking.
 func DisableDriverLocks() Option {
@@ -44,3 +124,152 @@ func DisableLogger() Option {
 		return nil
 	}
 }
+
+// OrderBy sets the Comparator used to order migrations by their ID. Available
+// and applied migrations are both ordered using it. By default, LexicalOrder is
+// used.
+func OrderBy(cmp Comparator) Option {
+	return func(e *exec) error {
+		if cmp == nil {
+			return fmt.Errorf("adapt: OrderBy comparator cannot be nil")
+		}
+		e.optOrder = cmp
+		return nil
+	}
+}
+
+// StrictOrdering forbids applying migrations out of order. By default, adapt
+// fills "holes" (available migrations that sort before the last applied one,
+// most often caused by merging branches) by applying them. With StrictOrdering
+// Migrate aborts with ErrIntegrityProtection instead and reports the offending
+// migration IDs.
+func StrictOrdering() Option {
+	return func(e *exec) error {
+		e.optStrictOrdering = true
+		return nil
+	}
+}
+
+// IncludeTags restricts tagged migrations to those having at least one of the
+// given tags (see ParsedMigration.Tags and Hook.Tags). Migrations without tags
+// are always included. Filtered migrations are neither applied nor treated as
+// holes, and reported as StatusSkipped by Status. Multiple calls add up.
+func IncludeTags(tags ...string) Option {
+	return func(e *exec) error {
+		if len(tags) == 0 {
+			return fmt.Errorf("adapt: IncludeTags requires at least one tag")
+		}
+		e.optTags.include = append(e.optTags.include, tags...)
+		return nil
+	}
+}
+
+// ExcludeTags filters all migrations having at least one of the given tags.
+// ExcludeTags takes precedence over IncludeTags. Filtered migrations are
+// handled like described in IncludeTags. Multiple calls add up.
+func ExcludeTags(tags ...string) Option {
+	return func(e *exec) error {
+		if len(tags) == 0 {
+			return fmt.Errorf("adapt: ExcludeTags requires at least one tag")
+		}
+		e.optTags.exclude = append(e.optTags.exclude, tags...)
+		return nil
+	}
+}
+
+// ValidateIDs adds an IDValidator that every available migration ID must pass.
+// IDs are validated while preparing the local migrations, before anything is
+// executed. See ValidTimestampID, ValidFlywayID and ValidNumericID for validators
+// matching the built-in Comparator schemes.
+func ValidateIDs(validator IDValidator) Option {
+	return func(e *exec) error {
+		if validator == nil {
+			return fmt.Errorf("adapt: ValidateIDs validator cannot be nil")
+		}
+		e.optIDValidators = append(e.optIDValidators, validator)
+		return nil
+	}
+}
+
+// SQLDialect sets the Dialect SqlStatementsSource migrations are written in,
+// which enables dialect specific checks (see Validate and EnforcePolicy). By
+// default, the Dialect of NewPostgresDriver, NewMySQLDriver and
+// NewSQLiteDriver is detected automatically.
+func SQLDialect(dialect Dialect) Option {
+	return func(e *exec) error {
+		switch dialect {
+		case DialectPostgres, DialectMySQL, DialectSQLite:
+		default:
+			return fmt.Errorf("adapt: unsupported SQLDialect %q", dialect)
+		}
+		e.optDialect = dialect
+		return nil
+	}
+}
+
+// EnforcePolicy blocks Migrate from applying SqlStatementsSource migrations
+// containing statements that match one of rules (see DefaultPolicyRules),
+// unless the migration acknowledges them using the "-- +adapt AllowDestructive"
+// option. Rules restricted to specific dialects are only applied when the
+// Dialect is known (see SQLDialect). Nothing is applied when a needed migration
+// violates the policy, and Migrate returns ErrPolicyViolation. The stored Down
+// migrations of rollbacks (see Rollback and Migrate's automatic rollback of
+// unknown migrations) are checked the same way before anything is reverted, so
+// they must acknowledge destructive statements in their Down part. Hook
+// migrations execute Go code, which cannot be checked, and are never blocked.
+// Validate reports violations as DiagnosticPolicy. Multiple calls add up.
+func EnforcePolicy(rules ...PolicyRule) Option {
+	return func(e *exec) error {
+		if len(rules) == 0 {
+			return fmt.Errorf("adapt: EnforcePolicy requires at least one rule")
+		}
+		for _, rule := range rules {
+			if len(rule.Name) == 0 || rule.Match == nil {
+				return fmt.Errorf("adapt: EnforcePolicy rules require a Name and Match function")
+			}
+		}
+		e.optPolicy = append(e.optPolicy, rules...)
+		return nil
+	}
+}
+
+// RecordSchemaSnapshot stores the hash of the database schema after Migrate or
+// Rollback applied or reverted migrations (including Migrate's automatic
+// rollback of unknown migrations) as a JournalSchemaSnapshot entry, which is
+// later used by CheckDrift to detect changes made outside of adapt. When
+// nothing was applied or reverted the previous snapshot is kept. The Driver
+// must implement SchemaInspector and DriverJournal, which the PostgreSQL, MySQL
+// and SQLite drivers do.
+func RecordSchemaSnapshot() Option {
+	return func(e *exec) error {
+		e.optRecordSchemaSnapshot = true
+		return nil
+	}
+}
+
+// Observe registers an Observer that receives lifecycle events of the
+// operation, e.g. stages, applied migrations and executed statements. The
+// option can be passed multiple times to register several Observer, which are
+// called in the order they were registered.
+func Observe(observer Observer) Option {
+	return func(e *exec) error {
+		if observer == nil {
+			return fmt.Errorf("adapt: observer must not be nil")
+		}
+		e.optObservers = append(e.optObservers, observer)
+		return nil
+	}
+}
+
+// CollectMetrics records measurements of the operation in metrics, e.g. the
+// duration of every applied migration and executed statement.
+func CollectMetrics(metrics Metrics) Option {
+	return func(e *exec) error {
+		if metrics == nil {
+			return fmt.Errorf("adapt: metrics must not be nil")
+		}
+		e.optMetrics = metrics
+		e.optObservers = append(e.optObservers, &metricsObserver{metrics: metrics})
+		return nil
+	}
+}
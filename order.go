@@ -0,0 +1,239 @@
+package adapt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Comparator defines the ordering of migration IDs. It returns a negative number
+// when a must be applied before b, a positive number when a must be applied after
+// b and zero when both are equal. Every Comparator must provide a total order, so
+// IDs that don't follow the expected scheme still need to be placed somewhere.
+// Use an IDValidator to reject them.
+type Comparator func(a, b string) int
+
+// IDValidator checks that a migration ID follows a naming scheme. It returns a
+// non-nil error describing the violation otherwise.
+type IDValidator func(id string) error
+
+// LexicalOrder compares IDs byte-wise. It is the default Comparator and works
+// well for IDs with fixed-width prefixes (like "20240101_1200_init"), but sorts
+// "V10__b" in front of "V2__a".
+func LexicalOrder(a, b string) int {
+	return strings.Compare(a, b)
+}
+
+// NaturalOrder compares IDs by splitting them into runs of digits and runs of
+// non-digits. Digit runs are compared by their numeric value and all other runs
+// byte-wise, so "2_b" is sorted in front of "10_a". IDs that are equal by these
+// rules (like "01_a" and "1_a") fall back to LexicalOrder.
+func NaturalOrder(a, b string) int {
+	ra, rb := a, b
+	for len(ra) > 0 && len(rb) > 0 {
+		var ca, cb string
+		ca, ra = nextRun(ra)
+		cb, rb = nextRun(rb)
+
+		var c int
+		if isDigit(ca[0]) && isDigit(cb[0]) {
+			c = compareNumeric(ca, cb)
+		} else {
+			c = strings.Compare(ca, cb)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	if c := len(ra) - len(rb); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// timestampLayouts lists all supported timestamp prefixes for TimestampOrder and
+// ValidTimestampID. Longer layouts come first, so the longest matching prefix
+// is used.
+var timestampLayouts = []string{
+	"20060102150405",
+	"20060102_150405",
+	"20060102-150405",
+	"200601021504",
+	"20060102_1504",
+	"20060102-1504",
+	"20060102",
+}
+
+// TimestampOrder compares IDs by their timestamp prefix (for example
+// "20240101120000_init", "20240101_1200_init" or "20240101_init"). IDs with the
+// same timestamp are compared by the remaining part of the ID. IDs without a
+// valid timestamp prefix are sorted after all timestamped IDs.
+func TimestampOrder(a, b string) int {
+	ta, restA, okA := parseTimestampPrefix(a)
+	tb, restB, okB := parseTimestampPrefix(b)
+
+	switch {
+	case okA && okB:
+		if c := ta.Compare(tb); c != 0 {
+			return c
+		}
+		if c := strings.Compare(restA, restB); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case okA:
+		return -1
+	case okB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// ValidTimestampID is an IDValidator accepting IDs that start with one of the
+// timestamps supported by TimestampOrder.
+func ValidTimestampID(id string) error {
+	if _, _, ok := parseTimestampPrefix(id); !ok {
+		return fmt.Errorf("adapt: migration id %q doesn't start with a valid timestamp (like 20060102150405_name)", id)
+	}
+	return nil
+}
+
+func parseTimestampPrefix(id string) (t time.Time, rest string, ok bool) {
+	for _, layout := range timestampLayouts {
+		if len(id) < len(layout) {
+			continue
+		}
+
+		// the timestamp must either end the id or be followed by a separator
+		rest = id[len(layout):]
+		if len(rest) > 0 && rest[0] != '_' && rest[0] != '-' && rest[0] != '.' {
+			continue
+		}
+
+		t, err := time.Parse(layout, id[:len(layout)])
+		if err != nil {
+			continue
+		}
+		return t, rest, true
+	}
+	return time.Time{}, "", false
+}
+
+// FlywayOrder compares IDs following Flyway's "V<version>__<description>" naming
+// scheme. Versions consist of numeric parts separated by "." or "_" (like
+// "V1.2.10__add_index" or "V1_2__init") and are compared part by part, where
+// missing parts count as zero. IDs with the same version are compared by their
+// description. IDs not following the scheme are sorted after all valid IDs.
+func FlywayOrder(a, b string) int {
+	va, descA, okA := parseFlywayID(a)
+	vb, descB, okB := parseFlywayID(b)
+
+	switch {
+	case okA && okB:
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			pa, pb := "0", "0"
+			if i < len(va) {
+				pa = va[i]
+			}
+			if i < len(vb) {
+				pb = vb[i]
+			}
+			if c := compareNumeric(pa, pb); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(descA, descB); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case okA:
+		return -1
+	case okB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// ValidFlywayID is an IDValidator accepting IDs that follow the
+// "V<version>__<description>" scheme of FlywayOrder.
+func ValidFlywayID(id string) error {
+	if _, _, ok := parseFlywayID(id); !ok {
+		return fmt.Errorf("adapt: migration id %q doesn't follow the V<version>__<description> scheme", id)
+	}
+	return nil
+}
+
+func parseFlywayID(id string) (version []string, description string, ok bool) {
+	if !strings.HasPrefix(id, "V") {
+		return nil, "", false
+	}
+
+	v, description, found := strings.Cut(id[1:], "__")
+	if !found || len(v) == 0 || len(description) == 0 {
+		return nil, "", false
+	}
+
+	version = strings.FieldsFunc(v, func(r rune) bool {
+		return r == '.' || r == '_'
+	})
+	if len(version) == 0 {
+		return nil, "", false
+	}
+	for _, part := range version {
+		if !isNumeric(part) {
+			return nil, "", false
+		}
+	}
+
+	return version, description, true
+}
+
+// ValidNumericID is an IDValidator accepting IDs that start with a number (like
+// "1_init" or "0002_add_index"), as expected by NaturalOrder.
+func ValidNumericID(id string) error {
+	if len(id) == 0 || !isDigit(id[0]) {
+		return fmt.Errorf("adapt: migration id %q doesn't start with a number", id)
+	}
+	return nil
+}
+
+// nextRun splits s into its first run of either digits or non-digits and the
+// remaining string.
+func nextRun(s string) (run string, rest string) {
+	digits := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareNumeric compares two strings of digits by their numeric value without
+// parsing them, so arbitrary long numbers are supported.
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if c := len(a) - len(b); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,70 @@
+package adapt
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestComparators(t *testing.T) {
+	tests := []struct {
+		name string
+		cmp  Comparator
+		ids  []string
+		want []string
+	}{
+		{"lexical", LexicalOrder,
+			[]string{"V2__b", "V10__a", "V1__c"},
+			[]string{"V10__a", "V1__c", "V2__b"}},
+		{"natural", NaturalOrder,
+			[]string{"10_a", "2_b", "1_c", "01_c", "1_b", "b", "a10", "a9"},
+			[]string{"1_b", "01_c", "1_c", "2_b", "10_a", "a9", "a10", "b"}},
+		{"timestamp", TimestampOrder,
+			[]string{"20240102_init", "20240101_1300_b", "20240101120000_a", "noprefix", "20240101_1200_c"},
+			[]string{"20240101120000_a", "20240101_1200_c", "20240101_1300_b", "20240102_init", "noprefix"}},
+		{"flyway", FlywayOrder,
+			[]string{"V10__a", "V2__b", "V1.10__x", "V1.2__y", "V1_2_1__z", "V1__c", "R__view", "V1.2__a"},
+			[]string{"V1__c", "V1.2__a", "V1.2__y", "V1_2_1__z", "V1.10__x", "V2__b", "V10__a", "R__view"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := append([]string(nil), tt.ids...)
+			sort.Slice(got, func(i, j int) bool {
+				return tt.cmp(got[i], got[j]) < 0
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sorted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator IDValidator
+		id        string
+		wantErr   bool
+	}{
+		{"timestamp seconds", ValidTimestampID, "20240101120000_init", false},
+		{"timestamp minutes", ValidTimestampID, "20240101_1200_init", false},
+		{"timestamp date only", ValidTimestampID, "20240101", false},
+		{"timestamp invalid month", ValidTimestampID, "20241301_init", true},
+		{"timestamp missing separator", ValidTimestampID, "20240101init", true},
+		{"timestamp missing", ValidTimestampID, "init", true},
+		{"flyway", ValidFlywayID, "V1.2.3__init", false},
+		{"flyway underscore version", ValidFlywayID, "V1_2__init", false},
+		{"flyway missing description", ValidFlywayID, "V1__", true},
+		{"flyway non numeric version", ValidFlywayID, "V1a__init", true},
+		{"flyway missing prefix", ValidFlywayID, "1__init", true},
+		{"numeric", ValidNumericID, "0001_init", false},
+		{"numeric missing", ValidNumericID, "init_1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.validator(tt.id); (err != nil) != tt.wantErr {
+				t.Errorf("validator(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,11 +3,8 @@ package adapt
 import (
 	"bufio"
 	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 )
 
@@ -15,25 +12,29 @@ import (
 type ParsedMigration struct {
 	UseTx bool     `json:"UseTransaction"`
 	Stmts []string `json:"Statements"`
-}
-
-// Hash calculates a unique hash for the ParsedMigration. It includes the UseTx
-// field and every single statement from the Stmts field
-func (m *ParsedMigration) Hash() *string {
-	hash := sha256.New()
-	hash.Write([]byte(strconv.FormatBool(m.UseTx)))
-	for _, stmt := range m.Stmts {
-		// hash.Write never returns an error as to it's documentation
-		_, _ = hash.Write([]byte(stmt))
-	}
-	hashStr := hex.EncodeToString(hash.Sum([]byte{}))
-	return &hashStr
+	// Replaces lists the IDs of migrations that were squashed into this
+	// migration. It is set by the "-- +adapt Replaces <id>,<id>" option.
+	Replaces []string `json:"Replaces,omitempty"`
+	// DependsOn lists the IDs of migrations that must be applied before this
+	// migration. It is set by the "-- +adapt DependsOn <id>,<id>" option.
+	DependsOn []string `json:"DependsOn,omitempty"`
+	// Tags lists labels used to filter migrations (see IncludeTags and
+	// ExcludeTags). It is set by the "-- +adapt Tags <tag>,<tag>" option.
+	Tags []string `json:"Tags,omitempty"`
+	// Repeatable marks the migration to be applied again whenever its content
+	// changes (see RepeatablePrefix). It is set by the "-- +adapt Repeatable"
+	// option.
+	Repeatable bool `json:"Repeatable,omitempty"`
+	// AllowDestructive acknowledges statements flagged by the PolicyRule set
+	// of EnforcePolicy. It is set by the "-- +adapt AllowDestructive" option.
+	AllowDestructive bool `json:"AllowDestructive,omitempty"`
 }
 
 // Parse scans everything from an io.Reader into a ParsedMigration structure, while
 // preserving SQL-specific structures like multi-line statements (procedures). It
 // also checks for special "-- +adapt" options at the beginning of the file, like
-// "NoTransaction".
+// "NoTransaction", "Repeatable", "AllowDestructive", "Replaces <id>,<id>",
+// "DependsOn <id>,<id>" or "Tags <tag>,<tag>".
 //
 // The following example should give you an overview how Parse works. Given the
 // following file-content:
@@ -91,12 +92,51 @@ func Parse(r io.Reader) (*ParsedMigration, error) {
 
 		cmdPrefix := "-- +adapt "
 		if strings.HasPrefix(trimmedLine, cmdPrefix) {
-			switch option := strings.TrimPrefix(trimmedLine, cmdPrefix); option {
+			option := strings.TrimPrefix(trimmedLine, cmdPrefix)
+			name, value, _ := strings.Cut(option, " ")
+			switch name {
 			case "NoTransaction":
 				if len(p.Stmts) > 0 || buf.Len() > 0 {
 					return nil, fmt.Errorf("adapt/Parse: NoTransaction option must be in the first line of the file")
 				}
 				p.UseTx = false
+			case "Repeatable":
+				if len(p.Stmts) > 0 || buf.Len() > 0 {
+					return nil, fmt.Errorf("adapt/Parse: Repeatable option must be in front of all statements")
+				}
+				p.Repeatable = true
+			case "AllowDestructive":
+				if len(p.Stmts) > 0 || buf.Len() > 0 {
+					return nil, fmt.Errorf("adapt/Parse: AllowDestructive option must be in front of all statements")
+				}
+				p.AllowDestructive = true
+			case "Replaces":
+				if len(p.Stmts) > 0 || buf.Len() > 0 {
+					return nil, fmt.Errorf("adapt/Parse: Replaces option must be in front of all statements")
+				}
+				ids := parseOptionList(value)
+				if len(ids) == 0 {
+					return nil, fmt.Errorf("adapt/Parse: Replaces option requires at least one id")
+				}
+				p.Replaces = append(p.Replaces, ids...)
+			case "DependsOn":
+				if len(p.Stmts) > 0 || buf.Len() > 0 {
+					return nil, fmt.Errorf("adapt/Parse: DependsOn option must be in front of all statements")
+				}
+				ids := parseOptionList(value)
+				if len(ids) == 0 {
+					return nil, fmt.Errorf("adapt/Parse: DependsOn option requires at least one id")
+				}
+				p.DependsOn = append(p.DependsOn, ids...)
+			case "Tags":
+				if len(p.Stmts) > 0 || buf.Len() > 0 {
+					return nil, fmt.Errorf("adapt/Parse: Tags option must be in front of all statements")
+				}
+				tags := parseOptionList(value)
+				if len(tags) == 0 {
+					return nil, fmt.Errorf("adapt/Parse: Tags option requires at least one tag")
+				}
+				p.Tags = append(p.Tags, tags...)
 			case "BeginStatement":
 				inStatement = true
 			case "EndStatement":
@@ -150,6 +190,111 @@ func Parse(r io.Reader) (*ParsedMigration, error) {
 	return p, nil
 }
 
+// ParseUpDown scans a single file that contains both directions of a migration.
+// The Up and Down parts are introduced by "-- +adapt Up" and "-- +adapt Down"
+// marker lines and can appear in any order. Everything in front of the first
+// marker must be empty or a comment. Each part is parsed using Parse, so all
+// other "-- +adapt" options (like "NoTransaction") are supported at the start
+// of the respective part.
+//
+//	-- +adapt Up
+//	CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));
+//
+//	-- +adapt Down
+//	DROP TABLE accounts;
+//
+// When the file doesn't contain a Down part, the returned down ParsedMigration
+// is nil.
+func ParseUpDown(r io.Reader) (up *ParsedMigration, down *ParsedMigration, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(scanLines)
+
+	var upBuf, downBuf strings.Builder
+	var current *strings.Builder
+	var hasUp, hasDown bool
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmedLine := strings.TrimSpace(line)
+
+		switch trimmedLine {
+		case "-- +adapt Up":
+			if hasUp {
+				return nil, nil, fmt.Errorf("adapt/ParseUpDown: Up marker must only be used once")
+			}
+			hasUp = true
+			current = &upBuf
+			continue
+		case "-- +adapt Down":
+			if hasDown {
+				return nil, nil, fmt.Errorf("adapt/ParseUpDown: Down marker must only be used once")
+			}
+			hasDown = true
+			current = &downBuf
+			continue
+		}
+
+		if current == nil {
+			// only empty lines and comments are allowed in front of the first marker
+			if len(trimmedLine) > 0 && !strings.HasPrefix(trimmedLine, "-- ") {
+				return nil, nil, fmt.Errorf("adapt/ParseUpDown: statement found before first Up or Down marker")
+			}
+			continue
+		}
+
+		_, _ = current.WriteString(line) // error is always nil according to Go documentation
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if !hasUp {
+		return nil, nil, fmt.Errorf("adapt/ParseUpDown: missing Up marker")
+	}
+
+	up, err = Parse(strings.NewReader(upBuf.String()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if hasDown {
+		down, err = Parse(strings.NewReader(downBuf.String()))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return up, down, nil
+}
+
+// hasUpDownMarkers reports whether the content of r contains at least one
+// "-- +adapt Up" marker line, which identifies files that must be parsed using
+// ParseUpDown.
+func hasUpDownMarkers(r io.Reader) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(scanLines)
+
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "-- +adapt Up" {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// parseOptionList splits the comma separated value of a "-- +adapt" option and
+// drops empty elements.
+func parseOptionList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if atEOF && len(data) == 0 {
 		return 0, nil, nil
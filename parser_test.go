@@ -51,6 +51,44 @@ CREATE DATABASE IF NOT EXISTS testdb;`)}, nil, true},
 		{"Option NoTransaction not in first line", args{strings.NewReader(`
 CREATE DATABASE IF NOT EXISTS testdb;
 -- +adapt NoTransaction`)}, nil, true},
+		{"Option DependsOn", args{strings.NewReader(`
+-- +adapt DependsOn 20240101_1200_init, 20240102_1200_users
+-- +adapt DependsOn 20240103_1200_roles
+CREATE INDEX users_role ON users (role);`)}, &ParsedMigration{
+			UseTx:     true,
+			Stmts:     []string{"CREATE INDEX users_role ON users (role);"},
+			DependsOn: []string{"20240101_1200_init", "20240102_1200_users", "20240103_1200_roles"},
+		}, false},
+		{"Option Tags", args{strings.NewReader(`
+-- +adapt Tags dev, seed
+INSERT INTO users (name) VALUES ('dev');`)}, &ParsedMigration{
+			UseTx: true,
+			Stmts: []string{"INSERT INTO users (name) VALUES ('dev');"},
+			Tags:  []string{"dev", "seed"},
+		}, false},
+		{"Option Repeatable", args{strings.NewReader(`
+-- +adapt Repeatable
+CREATE OR REPLACE VIEW active_users AS SELECT * FROM users WHERE active;`)}, &ParsedMigration{
+			UseTx:      true,
+			Stmts:      []string{"CREATE OR REPLACE VIEW active_users AS SELECT * FROM users WHERE active;"},
+			Repeatable: true,
+		}, false},
+		{"Option AllowDestructive", args{strings.NewReader(`
+-- +adapt AllowDestructive
+DROP TABLE legacy_users;`)}, &ParsedMigration{
+			UseTx:            true,
+			Stmts:            []string{"DROP TABLE legacy_users;"},
+			AllowDestructive: true,
+		}, false},
+		{"Option AllowDestructive after statement", args{strings.NewReader(`
+DROP TABLE legacy_users;
+-- +adapt AllowDestructive`)}, nil, true},
+		{"Option DependsOn without ids", args{strings.NewReader(`
+-- +adapt DependsOn
+CREATE INDEX users_role ON users (role);`)}, nil, true},
+		{"Option DependsOn after statement", args{strings.NewReader(`
+CREATE INDEX users_role ON users (role);
+-- +adapt DependsOn 20240101_1200_init`)}, nil, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -65,3 +103,82 @@ CREATE DATABASE IF NOT EXISTS testdb;
 		})
 	}
 }
+
+func TestParseUpDown(t *testing.T) {
+	type args struct {
+		r io.Reader
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantUp   *ParsedMigration
+		wantDown *ParsedMigration
+		wantErr  bool
+	}{
+		{"Up and Down", args{strings.NewReader(`
+-- create accounts table
+-- +adapt Up
+CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));
+CREATE INDEX accounts_idx ON accounts (id);
+
+-- +adapt Down
+-- +adapt NoTransaction
+DROP TABLE accounts;
+`)}, &ParsedMigration{
+			UseTx: true,
+			Stmts: []string{
+				"CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));",
+				"CREATE INDEX accounts_idx ON accounts (id);",
+			},
+		}, &ParsedMigration{
+			UseTx: false,
+			Stmts: []string{
+				"DROP TABLE accounts;",
+			},
+		}, false},
+		{"Down before Up", args{strings.NewReader(`
+-- +adapt Down
+DROP TABLE accounts;
+-- +adapt Up
+CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));`)}, &ParsedMigration{
+			UseTx: true,
+			Stmts: []string{"CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));"},
+		}, &ParsedMigration{
+			UseTx: true,
+			Stmts: []string{"DROP TABLE accounts;"},
+		}, false},
+		{"Only Up", args{strings.NewReader(`
+-- +adapt Up
+CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));`)}, &ParsedMigration{
+			UseTx: true,
+			Stmts: []string{"CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));"},
+		}, nil, false},
+		{"Missing Up", args{strings.NewReader(`
+-- +adapt Down
+DROP TABLE accounts;`)}, nil, nil, true},
+		{"Statement before marker", args{strings.NewReader(`
+CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));
+-- +adapt Up
+CREATE TABLE users (id INT NOT NULL, PRIMARY KEY (id));`)}, nil, nil, true},
+		{"Duplicated Up", args{strings.NewReader(`
+-- +adapt Up
+CREATE TABLE accounts (id INT NOT NULL, PRIMARY KEY (id));
+-- +adapt Up
+CREATE TABLE users (id INT NOT NULL, PRIMARY KEY (id));`)}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUp, gotDown, err := ParseUpDown(tt.args.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseUpDown() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotUp, tt.wantUp) {
+				t.Errorf("ParseUpDown() gotUp = %v, want %v", gotUp, tt.wantUp)
+			}
+			if !reflect.DeepEqual(gotDown, tt.wantDown) {
+				t.Errorf("ParseUpDown() gotDown = %v, want %v", gotDown, tt.wantDown)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package adapt
+
+// MigrationPlan describes the changes Migrate would perform. It is created by
+// Plan.
+type MigrationPlan struct {
+	// Rollback lists applied migrations that aren't available anymore and
+	// would be reverted first, in the order they would be reverted.
+	Rollback []*Migration
+	// Apply lists the migrations that would be applied afterwards, in the
+	// order they would be applied. It includes changed repeatable migrations.
+	Apply []*AvailableMigration
+}
+
+// Empty reports whether the MigrationPlan doesn't contain any changes.
+func (p *MigrationPlan) Empty() bool {
+	return len(p.Rollback) == 0 && len(p.Apply) == 0
+}
+
+/*
+Plan calculates the changes Migrate would perform with the same arguments,
+without changing anything. Integrity violations Migrate would abort with are
+returned as errors. Migrations recorded without executing them (see
+BaselineOnEmptyMeta and ParsedMigration.Replaces) aren't part of the plan.
+
+Example:
+
+	plan, err := adapt.Plan(
+		adapt.NewPostgresDriver(db),
+		adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	for _, m := range plan.Apply {
+		fmt.Println("would apply", m.ID)
+	}
+*/
+func Plan(driver Driver, sources SourceCollection, options ...Option) (*MigrationPlan, error) {
+	e, err := newExec("adapt/plan", driver, sources, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *MigrationPlan
+	err = e.runWith(func() error {
+		var stageErr error
+		plan, stageErr = e.stagePlan()
+		return stageErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,114 @@
+package adapt
+
+import (
+	"regexp"
+	"slices"
+)
+
+// PolicyRule flags dangerous statements of SqlStatementsSource migrations. See
+// EnforcePolicy and DefaultPolicyRules.
+type PolicyRule struct {
+	// Name identifies the rule in logs and diagnostics, like "drop_table"
+	Name string
+	// Dialects restricts the rule to the listed Dialect values. When empty the
+	// rule applies to all dialects.
+	Dialects []Dialect
+	// Match reports whether a statement violates the rule. The statement is
+	// normalized: comment lines are removed, whitespace sequences are collapsed
+	// to a single space and all letters are upper-cased.
+	Match func(stmt string) bool
+	// Reason explains why matching statements are dangerous
+	Reason string
+}
+
+func (r PolicyRule) appliesTo(dialect Dialect) bool {
+	return len(r.Dialects) == 0 || slices.Contains(r.Dialects, dialect)
+}
+
+// MatchPattern returns a PolicyRule.Match function that reports whether the
+// normalized statement matches the regular expression expr. It panics if expr
+// cannot be compiled.
+func MatchPattern(expr string) func(stmt string) bool {
+	re := regexp.MustCompile(expr)
+	return re.MatchString
+}
+
+var wherePattern = regexp.MustCompile(`\bWHERE\b`)
+
+/*
+DefaultPolicyRules returns the built-in PolicyRule set, which flags destructive
+and table locking statements:
+
+  - drop_table: DROP TABLE, DROP SCHEMA and DROP DATABASE
+  - drop_column: ALTER TABLE ... DROP COLUMN
+  - truncate: TRUNCATE
+  - delete_without_where: DELETE without a WHERE clause
+  - update_without_where: UPDATE without a WHERE clause
+  - alter_column_type: column type changes (PostgreSQL and MySQL)
+  - add_column_default: ALTER TABLE ... ADD COLUMN ... DEFAULT (PostgreSQL and MySQL)
+  - create_index_blocking: CREATE INDEX without CONCURRENTLY (PostgreSQL)
+*/
+func DefaultPolicyRules() []PolicyRule {
+	isDelete := MatchPattern(`^DELETE\b`)
+	isUpdate := MatchPattern(`^UPDATE\b`)
+	isCreateIndex := MatchPattern(`^CREATE (UNIQUE )?INDEX\b`)
+	isConcurrent := MatchPattern(`^CREATE (UNIQUE )?INDEX CONCURRENTLY\b`)
+
+	return []PolicyRule{
+		{
+			Name:   "drop_table",
+			Match:  MatchPattern(`^DROP (TABLE|SCHEMA|DATABASE)\b`),
+			Reason: "drops data irrecoverably",
+		},
+		{
+			Name:   "drop_column",
+			Match:  MatchPattern(`^ALTER TABLE\b.*\bDROP COLUMN\b`),
+			Reason: "drops data irrecoverably",
+		},
+		{
+			Name:   "truncate",
+			Match:  MatchPattern(`^TRUNCATE\b`),
+			Reason: "deletes all rows of the table",
+		},
+		{
+			Name: "delete_without_where",
+			Match: func(stmt string) bool {
+				return isDelete(stmt) && !wherePattern.MatchString(stmt)
+			},
+			Reason: "deletes all rows of the table",
+		},
+		{
+			Name: "update_without_where",
+			Match: func(stmt string) bool {
+				return isUpdate(stmt) && !wherePattern.MatchString(stmt)
+			},
+			Reason: "updates all rows of the table",
+		},
+		{
+			Name:     "alter_column_type",
+			Dialects: []Dialect{DialectPostgres},
+			Match:    MatchPattern(`^ALTER TABLE\b.*\bALTER (COLUMN )?\S+ (SET DATA )?TYPE\b`),
+			Reason:   "can rewrite the table while holding an exclusive lock",
+		},
+		{
+			Name:     "alter_column_type",
+			Dialects: []Dialect{DialectMySQL},
+			Match:    MatchPattern(`^ALTER TABLE\b.*\b(MODIFY|CHANGE)\b`),
+			Reason:   "can rewrite the table while blocking writes",
+		},
+		{
+			Name:     "add_column_default",
+			Dialects: []Dialect{DialectPostgres, DialectMySQL},
+			Match:    MatchPattern(`^ALTER TABLE\b.*\bADD (COLUMN )?.*\bDEFAULT\b`),
+			Reason:   "can rewrite the table while holding a lock on older database versions or with volatile defaults",
+		},
+		{
+			Name:     "create_index_blocking",
+			Dialects: []Dialect{DialectPostgres},
+			Match: func(stmt string) bool {
+				return isCreateIndex(stmt) && !isConcurrent(stmt)
+			},
+			Reason: "blocks writes to the table until the index is built (use CREATE INDEX CONCURRENTLY)",
+		},
+	}
+}
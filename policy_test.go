@@ -0,0 +1,95 @@
+package adapt
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPolicyRules(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		dialect Dialect
+		want    []string
+	}{
+		{"DROP TABLE users;", DialectPostgres, []string{"drop_table"}},
+		{"drop   schema\n  legacy cascade;", "", []string{"drop_table"}},
+		{"DROP INDEX users_email;", DialectPostgres, nil},
+		{"ALTER TABLE users DROP COLUMN email;", DialectSQLite, []string{"drop_column"}},
+		{"TRUNCATE users;", DialectMySQL, []string{"truncate"}},
+		{"DELETE FROM users;", DialectPostgres, []string{"delete_without_where"}},
+		{"DELETE FROM users WHERE id = 1;", DialectPostgres, nil},
+		{"UPDATE users SET active = false;", DialectPostgres, []string{"update_without_where"}},
+		{"UPDATE users SET active = false\nWHERE id = 1;", DialectPostgres, nil},
+		{"ALTER TABLE users ALTER COLUMN id TYPE BIGINT;", DialectPostgres, []string{"alter_column_type"}},
+		{"ALTER TABLE users ALTER COLUMN id TYPE BIGINT;", DialectMySQL, nil},
+		{"ALTER TABLE users MODIFY COLUMN id BIGINT;", DialectMySQL, []string{"alter_column_type"}},
+		{"ALTER TABLE users ADD COLUMN active BOOLEAN DEFAULT true;", DialectPostgres, []string{"add_column_default"}},
+		{"ALTER TABLE users ADD COLUMN active BOOLEAN DEFAULT true;", DialectSQLite, nil},
+		{"ALTER TABLE users ADD COLUMN active BOOLEAN;", DialectPostgres, nil},
+		{"CREATE UNIQUE INDEX users_email ON users (email);", DialectPostgres, []string{"create_index_blocking"}},
+		{"CREATE INDEX CONCURRENTLY users_email ON users (email);", DialectPostgres, nil},
+		{"CREATE INDEX users_email ON users (email);", "", nil},
+		{"CREATE TABLE users (id INT);", DialectPostgres, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			var got []string
+			for _, v := range checkPolicy(&ParsedMigration{Stmts: []string{tt.stmt}}, tt.dialect, DefaultPolicyRules()) {
+				got = append(got, v.rule.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	acknowledged := &ParsedMigration{Stmts: []string{"DROP TABLE users;"}, AllowDestructive: true}
+	if got := checkPolicy(acknowledged, DialectPostgres, DefaultPolicyRules()); len(got) > 0 {
+		t.Errorf("checkPolicy() of acknowledged migration = %v, want none", got)
+	}
+}
+
+func TestMigrate_EnforcePolicy(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	var executed []string
+	hooks := NewCodeSource("20240101_1200_init", Hook{MigrateUp: func() error {
+		executed = append(executed, "20240101_1200_init")
+		return nil
+	}})
+
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{
+		hooks,
+		NewMemoryFSSource(map[string]string{
+			"20240102_1200_drop.up.sql": "DELETE FROM users;\nDROP TABLE users;",
+		}),
+	}, EnforcePolicy(DefaultPolicyRules()...), DisableLogger())
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("Migrate() error = %v, want %v", err, ErrPolicyViolation)
+	}
+	if !strings.Contains(err.Error(), "20240102_1200_drop") {
+		t.Errorf("Migrate() error = %v, want offending id listed", err)
+	}
+	if len(executed) > 0 {
+		t.Errorf("executed = %v, want nothing", executed)
+	}
+
+	// acknowledged migrations pass the policy, but the FileDriver cannot
+	// execute SQL statements
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{
+		hooks,
+		NewMemoryFSSource(map[string]string{
+			"20240102_1200_drop.up.sql": "-- +adapt AllowDestructive\nDELETE FROM users;\nDROP TABLE users;",
+		}),
+	}, EnforcePolicy(DefaultPolicyRules()...), DisableLogger())
+	if err == nil || errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Migrate() error = %v, want execution error", err)
+	}
+	if !reflect.DeepEqual(executed, []string{"20240101_1200_init"}) {
+		t.Errorf("executed = %v", executed)
+	}
+}
@@ -0,0 +1,135 @@
+package adapt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RepairOption configures the actions performed by Repair
+type RepairOption func(*repairConfig) error
+
+type repairConfig struct {
+	executor         string
+	reason           string
+	hashes           bool
+	markFinished     bool
+	deleteUnfinished bool
+	only             map[string]struct{}
+	options          []Option
+}
+
+// RepairBy sets the name of the person or program that performs the repair.
+// It is required and stored as JournalEntry.Executor for every action.
+func RepairBy(executor string) RepairOption {
+	return func(cfg *repairConfig) error {
+		cfg.executor = strings.TrimSpace(executor)
+		return nil
+	}
+}
+
+// RepairReason sets the reason why the repair is performed. It is required and
+// stored as JournalEntry.Reason for every action.
+func RepairReason(reason string) RepairOption {
+	return func(cfg *repairConfig) error {
+		cfg.reason = strings.TrimSpace(reason)
+		return nil
+	}
+}
+
+// RepairHashes replaces the stored hash of every applied migration whose hash
+// doesn't match the local migration with the local hash. Use it after harmless
+// changes (like whitespace edits) to already applied migrations caused an
+// ErrIntegrityProtection.
+func RepairHashes() RepairOption {
+	return func(cfg *repairConfig) error {
+		cfg.hashes = true
+		return nil
+	}
+}
+
+// RepairMarkUnfinishedAsFinished marks every migration that started but never
+// finished as finished. Use it when you verified manually that the migration
+// was applied completely.
+func RepairMarkUnfinishedAsFinished() RepairOption {
+	return func(cfg *repairConfig) error {
+		cfg.markFinished = true
+		return nil
+	}
+}
+
+// RepairDeleteUnfinished deletes the meta-data of every migration that started
+// but never finished, so that it is applied again during the next Migrate. Use
+// it when you verified manually that the migration didn't change anything.
+// The Driver must implement DriverMigrationRemover.
+func RepairDeleteUnfinished() RepairOption {
+	return func(cfg *repairConfig) error {
+		cfg.deleteUnfinished = true
+		return nil
+	}
+}
+
+// RepairOnly restricts all repair actions to the migrations with the passed
+// IDs.
+func RepairOnly(ids ...string) RepairOption {
+	return func(cfg *repairConfig) error {
+		if cfg.only == nil {
+			cfg.only = make(map[string]struct{}, len(ids))
+		}
+		for _, id := range ids {
+			cfg.only[id] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// RepairWithOptions passes Option values (like CustomLogger) to the execution
+// pipeline used by Repair.
+func RepairWithOptions(options ...Option) RepairOption {
+	return func(cfg *repairConfig) error {
+		cfg.options = append(cfg.options, options...)
+		return nil
+	}
+}
+
+/*
+Repair fixes the meta-storage of a Driver in situations where Migrate refuses to
+continue to protect integrity, without hand-editing the meta-storage. Every
+performed action is recorded as JournalEntry, therefore the Driver must
+implement DriverJournal. The performed actions are returned.
+
+Example:
+
+	entries, err := adapt.Repair(
+		adapt.NewFileDriver("migrations.json"),
+		adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		},
+		adapt.RepairBy("jane.doe"),
+		adapt.RepairReason("whitespace-only edit of 20240101_1200_init.up.sql"),
+		adapt.RepairHashes(),
+	)
+*/
+func Repair(driver Driver, sources SourceCollection, options ...RepairOption) ([]*JournalEntry, error) {
+	cfg := &repairConfig{}
+	for _, opt := range options {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.executor) == 0 {
+		return nil, fmt.Errorf("adapt: repair requires an executor (see RepairBy)")
+	}
+	if len(cfg.reason) == 0 {
+		return nil, fmt.Errorf("adapt: repair requires a reason (see RepairReason)")
+	}
+	if cfg.markFinished && cfg.deleteUnfinished {
+		return nil, fmt.Errorf("adapt: unfinished migrations can either be marked as finished or deleted, but not both")
+	}
+
+	e, err := newExec(cfg.executor, driver, sources, cfg.options...)
+	if err != nil {
+		return nil, err
+	}
+	return e.runRepair(cfg)
+}
@@ -0,0 +1,9 @@
+package adapt
+
+// RepeatablePrefix identifies repeatable migrations by their ID (like
+// "R__views.sql"). Repeatable migrations can also be marked using
+// ParsedMigration.Repeatable or Hook.Repeatable. They are applied after all
+// other migrations, whenever their Hash differs from the one stored by their
+// last run. Every run is recorded as a JournalEntry of kind
+// JournalRepeatableRun, when the Driver implements DriverJournal.
+const RepeatablePrefix = "R__"
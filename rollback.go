@@ -0,0 +1,37 @@
+package adapt
+
+/*
+Rollback reverts the last steps applied migrations using their stored Down
+migrations. Migrations are reverted in the reverse order they were applied in
+(see Migration.Started and Migration.DeploymentOrder). Repeatable migrations are
+never reverted. Rollback requires a DatabaseDriver and aborts without changing
+anything, when one of the selected migrations doesn't provide a Down migration.
+The reverted migrations are returned in the order they were reverted.
+
+Example:
+
+	reverted, err := adapt.Rollback(
+		adapt.NewPostgresDriver(db),
+		adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		},
+		1,
+	)
+*/
+func Rollback(driver Driver, sources SourceCollection, steps int, options ...Option) ([]*Migration, error) {
+	e, err := newExec("adapt/rollback", driver, sources, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []*Migration
+	err = e.runWith(func() error {
+		var stageErr error
+		reverted, stageErr = e.stageRollbackSteps(steps)
+		return stageErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
@@ -0,0 +1,330 @@
+package adapt
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NamingScheme generates the ID of a new migration from its slugged
+// description (lowercase letters, digits and dashes) and the IDs of all existing
+// migrations. It is used by NewMigrationFiles.
+type NamingScheme func(existing []string, description string) (string, error)
+
+// TimestampNaming returns a NamingScheme that prefixes the description with the
+// current UTC time formatted using layout, like "20240101_1200_add-users". When
+// layout is empty "20060102_1504" is used, which matches TimestampOrder and
+// ValidTimestampID. The layout must sort lexically in chronological order. If
+// an existing ID uses the same or a later timestamp (e.g. a second migration
+// created within the same minute, or clock skew between developers), the
+// timestamp of the latest existing ID is advanced by the smallest unit of
+// layout instead, so that the new migration always sorts last.
+func TimestampNaming(layout string) NamingScheme {
+	if len(layout) == 0 {
+		layout = "20060102_1504"
+	}
+	return func(existing []string, description string) (string, error) {
+		prefix := time.Now().UTC().Format(layout)
+
+		latest := ""
+		for _, id := range existing {
+			if len(id) <= len(prefix) || id[len(prefix)] != '_' {
+				continue
+			}
+			if _, err := time.Parse(layout, id[:len(prefix)]); err == nil && id[:len(prefix)] > latest {
+				latest = id[:len(prefix)]
+			}
+		}
+
+		if prefix <= latest {
+			t, _ := time.Parse(layout, latest)
+			next, ok := nextTimestamp(t, layout)
+			if !ok || next <= latest {
+				return "", fmt.Errorf("adapt: unable to generate timestamp after %q using layout %q", latest, layout)
+			}
+			prefix = next
+		}
+
+		return prefix + "_" + description, nil
+	}
+}
+
+// nextTimestamp advances t by the smallest unit represented in layout and
+// returns it formatted.
+func nextTimestamp(t time.Time, layout string) (string, bool) {
+	current := t.Format(layout)
+	for _, step := range []time.Duration{time.Nanosecond, time.Microsecond, time.Millisecond, time.Second, time.Minute, time.Hour, 24 * time.Hour} {
+		if next := t.Add(step).Format(layout); next != current {
+			return next, true
+		}
+	}
+	for _, next := range []time.Time{t.AddDate(0, 1, 0), t.AddDate(1, 0, 0)} {
+		if next.Format(layout) != current {
+			return next.Format(layout), true
+		}
+	}
+	return "", false
+}
+
+// FlywayNaming returns a NamingScheme creating IDs like "V3__add_users" for
+// FlywayOrder. The major version is incremented from the highest major version
+// of all existing IDs following the scheme.
+func FlywayNaming() NamingScheme {
+	return func(existing []string, description string) (string, error) {
+		next := 1
+		for _, id := range existing {
+			version, _, ok := parseFlywayID(id)
+			if !ok {
+				continue
+			}
+			major, err := strconv.Atoi(version[0])
+			if err != nil {
+				return "", err
+			}
+			if major >= next {
+				next = major + 1
+			}
+		}
+		return fmt.Sprintf("V%d__%s", next, strings.ReplaceAll(description, "-", "_")), nil
+	}
+}
+
+// NumericNaming returns a NamingScheme creating IDs like "0003_add-users" for
+// NaturalOrder. The number is incremented from the highest numeric prefix of
+// all existing IDs and zero-padded to width digits.
+func NumericNaming(width int) NamingScheme {
+	return func(existing []string, description string) (string, error) {
+		next := 1
+		for _, id := range existing {
+			if len(id) == 0 || !isDigit(id[0]) {
+				continue
+			}
+			prefix, _ := nextRun(id)
+			n, err := strconv.Atoi(prefix)
+			if err != nil {
+				return "", err
+			}
+			if n >= next {
+				next = n + 1
+			}
+		}
+		return fmt.Sprintf("%0*d_%s", width, next, description), nil
+	}
+}
+
+// ScaffoldOption provides configuration values for NewMigrationFiles.
+type ScaffoldOption func(*scaffold) error
+
+type scaffold struct {
+	naming       NamingScheme
+	hookPackage  string
+	hookRegistry string
+	sources      SourceCollection
+}
+
+// ScaffoldNaming sets the NamingScheme for new migration IDs. By default,
+// TimestampNaming("") is used.
+func ScaffoldNaming(scheme NamingScheme) ScaffoldOption {
+	return func(s *scaffold) error {
+		if scheme == nil {
+			return fmt.Errorf("adapt: ScaffoldNaming scheme cannot be nil")
+		}
+		s.naming = scheme
+		return nil
+	}
+}
+
+// ScaffoldGoHook creates a Go file containing a Hook stub instead of
+// ".up.sql"/".down.sql" files. The file belongs to package pkg and registers
+// the Hook in the map variable registry (a map[string]adapt.Hook declared
+// elsewhere in the package), which is meant to be passed to
+// NewCodePackageSource.
+func ScaffoldGoHook(pkg string, registry string) ScaffoldOption {
+	return func(s *scaffold) error {
+		if len(pkg) == 0 || len(registry) == 0 {
+			return fmt.Errorf("adapt: ScaffoldGoHook package and registry cannot be empty")
+		}
+		s.hookPackage = pkg
+		s.hookRegistry = registry
+		return nil
+	}
+}
+
+// ScaffoldSources adds sources whose migration IDs must not collide with the
+// new migration, e.g. a NewCodePackageSource that isn't backed by the
+// directory. The Sources are initialized by NewMigrationFiles.
+func ScaffoldSources(sources ...Source) ScaffoldOption {
+	return func(s *scaffold) error {
+		s.sources = append(s.sources, sources...)
+		return nil
+	}
+}
+
+// scaffoldHookSuffix is the file suffix of Go Hook stubs created by
+// NewMigrationFiles. Using a dot keeps Go from interpreting parts of the ID as
+// build constraints (like "_test" or "_linux").
+const scaffoldHookSuffix = ".hook.go"
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+/*
+NewMigrationFiles creates the files of a new migration in dir and returns its ID
+and the created filenames. The ID is generated from description by the
+configured NamingScheme (see ScaffoldNaming). By default, an empty
+"<id>.up.sql" and "<id>.down.sql" file are created. With ScaffoldGoHook a Go
+file containing a Hook stub is created instead.
+
+NewMigrationFiles refuses to create a migration whose ID is already used by a
+SQL migration file in dir (unless ScaffoldGoHook is used), a previously created
+Hook stub in dir or one of the sources passed with ScaffoldSources. It never
+overwrites existing files.
+
+Example:
+
+	id, files, err := adapt.NewMigrationFiles("./sql", "encrypt user email")
+*/
+func NewMigrationFiles(dir string, description string, opts ...ScaffoldOption) (id string, files []string, err error) {
+	s := &scaffold{naming: TimestampNaming("")}
+	for _, opt := range opts {
+		if err = opt(s); err != nil {
+			return "", nil, err
+		}
+	}
+
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(description), "-"), "-")
+	if len(slug) == 0 {
+		return "", nil, fmt.Errorf("adapt: migration description must contain letters or digits")
+	}
+
+	existing, err := s.existingIDs(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, err = s.naming(existing, slug)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, e := range existing {
+		if e == id {
+			return "", nil, fmt.Errorf("adapt: migration id %q is already used", id)
+		}
+	}
+
+	type file struct{ name, content string }
+	newFiles := []file{
+		{id + ".up.sql", fmt.Sprintf("-- %s\n", id)},
+		{id + ".down.sql", fmt.Sprintf("-- %s\n", id)},
+	}
+	if len(s.hookPackage) > 0 {
+		newFiles = []file{{id + scaffoldHookSuffix, s.hookStub(id)}}
+	}
+
+	for _, f := range newFiles {
+		filename := filepath.Join(dir, f.name)
+		if err = writeNewFile(filename, f.content); err != nil {
+			return "", files, err
+		}
+		files = append(files, filename)
+	}
+
+	return id, files, nil
+}
+
+func (s *scaffold) existingIDs(dir string) ([]string, error) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Hook stubs are created inside Go packages, which cannot be scanned for
+	// SQL migrations
+	sources := s.sources
+	if len(s.hookPackage) == 0 {
+		ignore, err := scaffoldIgnorePatterns(dir)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(SourceCollection{NewFilesystemSource(dir, FilesystemIgnore(ignore...))}, sources...)
+	}
+
+	var existing []string
+	for _, src := range sources {
+		if err := src.Init(log); err != nil {
+			return nil, err
+		}
+		ids, err := src.ListMigrations()
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, ids...)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), scaffoldHookSuffix) {
+			existing = append(existing, strings.TrimSuffix(entry.Name(), scaffoldHookSuffix))
+		}
+	}
+
+	return existing, nil
+}
+
+// scaffoldIgnorePatterns returns patterns matching all files in dir that
+// aren't SQL migration files, like a README, .gitkeep or Hook stubs, so that
+// they don't fail the scan for existing migrations.
+func scaffoldIgnorePatterns(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			patterns = append(patterns, globEscaper.Replace(entry.Name()))
+		}
+	}
+	return patterns, nil
+}
+
+// globEscaper escapes all characters with a special meaning for path.Match
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+
+func (s *scaffold) hookStub(id string) string {
+	return fmt.Sprintf(`package %s
+
+import (
+	"database/sql"
+
+	"github.com/harwoeck/adapt"
+)
+
+func init() {
+	%s[%q] = adapt.Hook{
+		MigrateUpTx: func(tx *sql.Tx) error {
+			// TODO: implement migration %s
+			return nil
+		},
+	}
+}
+`, s.hookPackage, s.hookRegistry, id, id)
+}
+
+func writeNewFile(filename string, content string) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
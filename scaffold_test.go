@@ -0,0 +1,131 @@
+package adapt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewMigrationFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	id, files, err := NewMigrationFiles(dir, "Encrypt User E-Mail")
+	if err != nil {
+		t.Fatalf("NewMigrationFiles() unexpected error = %v", err)
+	}
+	if err = ValidTimestampID(id); err != nil {
+		t.Errorf("NewMigrationFiles() id = %q: %v", id, err)
+	}
+	if !strings.HasSuffix(id, "_encrypt-user-e-mail") {
+		t.Errorf("NewMigrationFiles() id = %q", id)
+	}
+	want := []string{filepath.Join(dir, id+".up.sql"), filepath.Join(dir, id+".down.sql")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("NewMigrationFiles() files = %v, want %v", files, want)
+	}
+
+	// a second migration within the same minute is created after the first one
+	next, _, err := NewMigrationFiles(dir, "encrypt user e-mail")
+	if err != nil {
+		t.Fatalf("NewMigrationFiles() second migration unexpected error = %v", err)
+	}
+	if next <= id {
+		t.Errorf("NewMigrationFiles() second id = %q, want after %q", next, id)
+	}
+
+	fixed := func(existing []string, description string) (string, error) { return id, nil }
+	if _, _, err = NewMigrationFiles(dir, "encrypt user e-mail", ScaffoldNaming(fixed)); err == nil {
+		t.Errorf("NewMigrationFiles() with colliding id expected error")
+	}
+
+	if _, _, err = NewMigrationFiles(dir, " -- "); err == nil {
+		t.Errorf("NewMigrationFiles() with empty description expected error")
+	}
+}
+
+func TestNewMigrationFiles_Naming(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		scheme   NamingScheme
+		want     string
+	}{
+		{"numeric empty", nil, NumericNaming(4), "0001_add-users"},
+		{"numeric next", []string{"0002_a.up.sql", "0010_b.up.sql"}, NumericNaming(4), "0011_add-users"},
+		{"flyway empty", nil, FlywayNaming(), "V1__add_users"},
+		{"flyway next", []string{"V2__a.up.sql", "V3_1__b.up.sql"}, FlywayNaming(), "V4__add_users"},
+		{"non-migration files", []string{"README.md", ".gitkeep", "0001_a.up.sql"}, NumericNaming(4), "0002_add-users"},
+		{"timestamp after later id", []string{"29990101_1200_a.up.sql", "20240101_1200_b.up.sql"}, TimestampNaming(""), "29990101_1201_add-users"},
+		{"timestamp after later day", []string{"29991231_a.up.sql"}, TimestampNaming("20060102"), "30000101_add-users"},
+		{"timestamp after later month", []string{"299912_a.up.sql"}, TimestampNaming("200601"), "300001_add-users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, _, err := NewMigrationFiles(dir, "Add users", ScaffoldNaming(tt.scheme))
+			if err != nil {
+				t.Fatalf("NewMigrationFiles() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NewMigrationFiles() id = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMigrationFiles_GoHook(t *testing.T) {
+	dir := t.TempDir()
+	opts := []ScaffoldOption{ScaffoldNaming(NumericNaming(3)), ScaffoldGoHook("migrations", "hooks")}
+
+	id, files, err := NewMigrationFiles(dir, "backfill", opts...)
+	if err != nil {
+		t.Fatalf("NewMigrationFiles() unexpected error = %v", err)
+	}
+	if id != "001_backfill" || !reflect.DeepEqual(files, []string{filepath.Join(dir, "001_backfill.hook.go")}) {
+		t.Fatalf("NewMigrationFiles() = %q, %v", id, files)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"package migrations\n", `hooks["001_backfill"] = adapt.Hook{`} {
+		if !strings.Contains(string(content), s) {
+			t.Errorf("hook stub doesn't contain %q:\n%s", s, content)
+		}
+	}
+
+	// existing stubs are taken into account for the next number
+	id, _, err = NewMigrationFiles(dir, "backfill", opts...)
+	if err != nil {
+		t.Fatalf("NewMigrationFiles() unexpected error = %v", err)
+	}
+	if id != "002_backfill" {
+		t.Errorf("NewMigrationFiles() id = %q, want 002_backfill", id)
+	}
+
+	fixed := func(existing []string, description string) (string, error) { return "001_backfill", nil }
+	_, _, err = NewMigrationFiles(dir, "backfill", ScaffoldNaming(fixed), ScaffoldGoHook("migrations", "hooks"))
+	if err == nil {
+		t.Errorf("NewMigrationFiles() with colliding hook stub expected error")
+	}
+}
+
+func TestNewMigrationFiles_Sources(t *testing.T) {
+	fixed := func(existing []string, description string) (string, error) { return "001_" + description, nil }
+	source := NewCodePackageSource(map[string]Hook{
+		"001_backfill": {MigrateUpTx: nil},
+	})
+
+	_, _, err := NewMigrationFiles(t.TempDir(), "backfill", ScaffoldNaming(fixed), ScaffoldSources(source))
+	if err == nil {
+		t.Errorf("NewMigrationFiles() colliding with ScaffoldSources expected error")
+	}
+}
@@ -0,0 +1,121 @@
+package adapt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SchemaSnapshot is a normalized description of a database schema. Equal
+// schemas always produce equal snapshots.
+type SchemaSnapshot struct {
+	// Objects lists a single line per schema element (like a column or an
+	// index) in sorted order
+	Objects []string
+}
+
+// HashPrefixSchemaV1 is the prefix of hashes calculated by SchemaSnapshot.Hash
+const HashPrefixSchemaV1 = "schemav1:"
+
+// NewSchemaSnapshot creates a SchemaSnapshot from unordered objects. Whitespace
+// sequences inside every object are collapsed to a single space.
+func NewSchemaSnapshot(objects []string) *SchemaSnapshot {
+	s := &SchemaSnapshot{Objects: make([]string, 0, len(objects))}
+	for _, object := range objects {
+		s.Objects = append(s.Objects, strings.Join(strings.Fields(object), " "))
+	}
+	slices.Sort(s.Objects)
+	return s
+}
+
+// Hash calculates a unique hash of all Objects, prefixed with
+// HashPrefixSchemaV1.
+func (s *SchemaSnapshot) Hash() string {
+	h := sha256.New()
+	for _, object := range s.Objects {
+		writeLengthPrefixed(h, object)
+	}
+	return HashPrefixSchemaV1 + hex.EncodeToString(h.Sum([]byte{}))
+}
+
+// String renders all Objects on separate lines
+func (s *SchemaSnapshot) String() string {
+	return strings.Join(s.Objects, "\n")
+}
+
+// SchemaInspector is an optional extension of Driver. It captures the current
+// schema of the database, which enables RecordSchemaSnapshot and CheckDrift.
+type SchemaInspector interface {
+	Driver
+	// InspectSchema must return a SchemaSnapshot of the current database
+	// schema. It should return an error wrapping errors.ErrUnsupported when
+	// the schema cannot be inspected.
+	InspectSchema() (*SchemaSnapshot, error)
+}
+
+// SqlStatementsSchemaInspector is an optional extension of
+// SqlStatementsDriver. When implemented the DatabaseDriver returned by
+// FromSqlStatementsDriver can inspect the database schema (see
+// SchemaInspector).
+type SqlStatementsSchemaInspector interface {
+	// SchemaQueries must return database queries whose result rows describe
+	// the schema. Every row is rendered as a single SchemaSnapshot object with
+	// tab separated columns.
+	SchemaQueries() (queries []string)
+}
+
+// DriftReport is the result of CheckDrift
+type DriftReport struct {
+	// Drifted reports whether the live schema differs from the last recorded
+	// snapshot
+	Drifted bool
+	// MigrationID is the ID of the last migration applied when the snapshot
+	// was recorded
+	MigrationID string
+	// Recorded is the time the snapshot was recorded
+	Recorded time.Time
+	// RecordedHash is the hash of the recorded snapshot
+	RecordedHash string
+	// Live is the snapshot of the current schema
+	Live *SchemaSnapshot
+	// LiveHash is the hash of Live
+	LiveHash string
+}
+
+/*
+CheckDrift compares the live schema of the database against the snapshot
+recorded after the last Migrate (see RecordSchemaSnapshot). Changes that were
+applied outside of adapt, like manual hotfixes, are reported as
+DriftReport.Drifted. The Driver must implement SchemaInspector and
+DriverJournal. When no snapshot was recorded yet ErrNoSchemaSnapshot is
+returned.
+
+Example:
+
+	report, err := adapt.CheckDrift(adapt.NewPostgresDriver(db))
+	if err != nil {
+		return err
+	}
+	if report.Drifted {
+		log.Printf("schema drifted since %s was applied", report.MigrationID)
+	}
+*/
+func CheckDrift(driver Driver, options ...Option) (*DriftReport, error) {
+	e, err := newExec("adapt/drift", driver, nil, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var report *DriftReport
+	err = e.runWith(func() error {
+		var stageErr error
+		report, stageErr = e.stageCheckDrift()
+		return stageErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
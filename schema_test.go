@@ -0,0 +1,97 @@
+package adapt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// schemaFileDriver adds a controllable SchemaInspector to the FileDriver
+type schemaFileDriver struct {
+	DriverJournal
+	objects *[]string
+}
+
+func (d *schemaFileDriver) InspectSchema() (*SchemaSnapshot, error) {
+	return NewSchemaSnapshot(*d.objects), nil
+}
+
+func TestNewSchemaSnapshot(t *testing.T) {
+	s := NewSchemaSnapshot([]string{"table\tusers\tCREATE TABLE users (\n    id INT\n)", "index\tusers_id"})
+	want := []string{"index users_id", "table users CREATE TABLE users ( id INT )"}
+	if !reflect.DeepEqual(s.Objects, want) {
+		t.Errorf("NewSchemaSnapshot() = %q, want %q", s.Objects, want)
+	}
+
+	if s.Hash() != NewSchemaSnapshot([]string{"index\tusers_id", "table users CREATE TABLE users ( id INT )"}).Hash() {
+		t.Errorf("Hash() of equal snapshots differs")
+	}
+	if s.Hash() == NewSchemaSnapshot([]string{"index users_id"}).Hash() {
+		t.Errorf("Hash() of different snapshots is equal")
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	objects := []string{"table users"}
+	driver := func() Driver {
+		return &schemaFileDriver{DriverJournal: NewFileDriver(filename).(DriverJournal), objects: &objects}
+	}
+	hooks := map[string]Hook{
+		"1_init": {MigrateUp: func() error { return nil }},
+	}
+	migrate := func() {
+		t.Helper()
+		err := Migrate("adapt-tester@v1.1.7", driver(), SourceCollection{NewCodePackageSource(hooks)}, RecordSchemaSnapshot(), DisableLogger())
+		if err != nil {
+			t.Fatalf("Migrate() unexpected error = %v", err)
+		}
+	}
+	checkDrift := func(want bool) {
+		t.Helper()
+		report, err := CheckDrift(driver(), DisableLogger())
+		if err != nil {
+			t.Fatalf("CheckDrift() unexpected error = %v", err)
+		}
+		if report.Drifted != want {
+			t.Errorf("CheckDrift() drifted = %v, want %v (report: %+v)", report.Drifted, want, report)
+		}
+	}
+
+	if _, err := CheckDrift(driver(), DisableLogger()); !errors.Is(err, ErrNoSchemaSnapshot) {
+		t.Fatalf("CheckDrift() without snapshot error = %v, want %v", err, ErrNoSchemaSnapshot)
+	}
+
+	migrate()
+	checkDrift(false)
+
+	// manual hotfix
+	objects = append(objects, "index users_email")
+	checkDrift(true)
+
+	// migrating without applying anything keeps the previous snapshot
+	migrate()
+	checkDrift(true)
+
+	// applying migrations records a new snapshot
+	hooks["2_email"] = Hook{MigrateUp: func() error { return nil }}
+	migrate()
+	checkDrift(false)
+
+	report, err := CheckDrift(driver(), DisableLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MigrationID != "2_email" || report.RecordedHash != NewSchemaSnapshot(objects).Hash() {
+		t.Errorf("CheckDrift() = %+v", report)
+	}
+
+	// drivers without SchemaInspector cannot record snapshots
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(hooks)}, RecordSchemaSnapshot(), DisableLogger())
+	if err == nil {
+		t.Errorf("Migrate() with RecordSchemaSnapshot and FileDriver expected error")
+	}
+}
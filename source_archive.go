@@ -0,0 +1,212 @@
+package adapt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+)
+
+// ArchiveFormat specifies the container format of an archive passed to
+// NewArchiveSource.
+type ArchiveFormat int
+
+const (
+	// ArchiveZip is a zip archive
+	ArchiveZip ArchiveFormat = iota
+	// ArchiveTar is an uncompressed tar archive
+	ArchiveTar
+	// ArchiveTarGzip is a gzip compressed tar archive (.tar.gz or .tgz)
+	ArchiveTarGzip
+)
+
+// ArchiveOption provides configuration values for a SqlStatementsSource created
+// using NewArchiveSource.
+type ArchiveOption func(*archiveSource) error
+
+// ArchiveDirectory sets the directory inside the archive that contains the
+// migration files. By default, the root of the archive is used.
+func ArchiveDirectory(directory string) ArchiveOption {
+	return func(src *archiveSource) error {
+		src.directory = directory
+		return nil
+	}
+}
+
+// ArchiveManifestName sets the file name of the Manifest inside the migration
+// directory of the archive. By default, "adapt-manifest.json" is used. When the
+// file exists every migration is verified against the hash pinned by the
+// Manifest during Init.
+func ArchiveManifestName(name string) ArchiveOption {
+	return func(src *archiveSource) error {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			return fmt.Errorf("adapt.archiveSource: manifest name cannot be empty")
+		}
+
+		src.manifestName = name
+		return nil
+	}
+}
+
+// ArchiveRequireManifest makes Init fail when the archive doesn't contain a
+// Manifest.
+func ArchiveRequireManifest() ArchiveOption {
+	return func(src *archiveSource) error {
+		src.optRequireManifest = true
+		return nil
+	}
+}
+
+// ArchiveFilesystemOptions passes FilesystemOption values to the filesystem
+// adapter used for the content of the archive, e.g. to scan it recursively.
+func ArchiveFilesystemOptions(opts ...FilesystemOption) ArchiveOption {
+	return func(src *archiveSource) error {
+		src.fsOpts = append(src.fsOpts, opts...)
+		return nil
+	}
+}
+
+// NewArchiveSource provides a new SqlStatementsSource that reads its migrations
+// from a zip or (gzipped) tar archive. The archive is read completely into
+// memory during Init. Migration files follow the same rules as for
+// NewFilesystemSource. When the archive contains a Manifest (see
+// ArchiveManifestName) all migrations are verified against it.
+func NewArchiveSource(r io.Reader, format ArchiveFormat, opts ...ArchiveOption) SqlStatementsSource {
+	return &archiveSource{
+		r:            r,
+		format:       format,
+		opts:         opts,
+		directory:    ".",
+		manifestName: "adapt-manifest.json",
+	}
+}
+
+type archiveSource struct {
+	SqlStatementsSource
+
+	r            io.Reader
+	format       ArchiveFormat
+	opts         []ArchiveOption
+	directory    string
+	manifestName string
+	fsOpts       []FilesystemOption
+	// fsys caches the content of the archive, as r can only be read once
+	fsys fs.FS
+
+	optRequireManifest bool
+}
+
+func (src *archiveSource) Init(log *slog.Logger) error {
+	for _, opt := range src.opts {
+		err := opt(src)
+		if err != nil {
+			log.Error("init failed due to option error", "error", err)
+			return err
+		}
+	}
+
+	if src.fsys == nil {
+		fsys, err := src.open()
+		if err != nil {
+			log.Error("unable to read archive", "error", err)
+			return err
+		}
+		src.fsys = fsys
+	}
+
+	manifest, err := readManifest(src.fsys, path.Join(src.directory, src.manifestName))
+	if err != nil {
+		log.Error("unable to read manifest from archive", "manifest", src.manifestName, "error", err)
+		return err
+	}
+	if manifest == nil && src.optRequireManifest {
+		log.Error("archive doesn't contain a manifest, but one is required", "manifest", src.manifestName)
+		return ErrInvalidSource
+	}
+
+	fsOpts := append([]FilesystemOption{FilesystemIgnore(src.manifestName)}, src.fsOpts...)
+	src.SqlStatementsSource = NewFSSource(src.fsys, src.directory, fsOpts...)
+	if err := src.SqlStatementsSource.Init(log); err != nil {
+		return err
+	}
+
+	if manifest != nil {
+		if err := manifest.verifySource(src.SqlStatementsSource, log); err != nil {
+			return err
+		}
+		log.Info("verified archive content against manifest", "migrations_amount", len(manifest.Migrations))
+	}
+
+	return nil
+}
+
+func (src *archiveSource) open() (fs.FS, error) {
+	switch src.format {
+	case ArchiveZip:
+		buf, err := io.ReadAll(src.r)
+		if err != nil {
+			return nil, err
+		}
+		return zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	case ArchiveTar:
+		return readTar(src.r)
+	case ArchiveTarGzip:
+		gz, err := gzip.NewReader(src.r)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		return readTar(gz)
+	default:
+		return nil, fmt.Errorf("adapt.archiveSource: unknown archive format %d", src.format)
+	}
+}
+
+func readTar(r io.Reader) (fs.FS, error) {
+	fsys := make(mapFS)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		fsys[name] = data
+	}
+
+	return fsys, nil
+}
+
+func readManifest(fsys fs.FS, name string) (*Manifest, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseManifest(data)
+}
@@ -0,0 +1,180 @@
+package adapt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func buildArchive(t *testing.T, format ArchiveFormat, files map[string]string) io.Reader {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	switch format {
+	case ArchiveZip:
+		zw := zip.NewWriter(buf)
+		for name, content := range files {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write([]byte(content))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case ArchiveTar, ArchiveTarGzip:
+		var w io.Writer = buf
+		var gz *gzip.Writer
+		if format == ArchiveTarGzip {
+			gz = gzip.NewWriter(buf)
+			w = gz
+		}
+		tw := tar.NewWriter(w)
+		for name, content := range files {
+			err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = tw.Write([]byte(content))
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if gz != nil {
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return buf
+}
+
+func hashOf(t *testing.T, content string) string {
+	t.Helper()
+
+	parsed, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *parsed.Hash()
+}
+
+// manifestFor pins the up content of all migrations and the down content of
+// the migrations that are present in downs
+func manifestFor(t *testing.T, ups map[string]string, downs map[string]string) string {
+	t.Helper()
+
+	m := &Manifest{}
+	for id, up := range ups {
+		entry := &ManifestEntry{ID: id, Hash: hashOf(t, up)}
+		if down, ok := downs[id]; ok {
+			entry.DownHash = hashOf(t, down)
+		}
+		m.Migrations = append(m.Migrations, entry)
+	}
+	buf, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+func TestNewArchiveSource(t *testing.T) {
+	files := map[string]string{
+		"schema/20240101_1200_init.up.sql":   "CREATE TABLE a (id INT);",
+		"schema/20240101_1200_init.down.sql": "DROP TABLE a;",
+		"schema/20240201_1200_users.up.sql":  "CREATE TABLE users (id INT);",
+	}
+	withManifest := func(manifest string) map[string]string {
+		m := map[string]string{"schema/adapt-manifest.json": manifest}
+		for k, v := range files {
+			m[k] = v
+		}
+		return m
+	}
+
+	downs := map[string]string{
+		"20240101_1200_init": files["schema/20240101_1200_init.down.sql"],
+	}
+	validManifest := manifestFor(t, map[string]string{
+		"20240101_1200_init":  files["schema/20240101_1200_init.up.sql"],
+		"20240201_1200_users": files["schema/20240201_1200_users.up.sql"],
+	}, downs)
+	tamperedManifest := manifestFor(t, map[string]string{
+		"20240101_1200_init":  files["schema/20240101_1200_init.up.sql"],
+		"20240201_1200_users": "CREATE TABLE users (id INT, name TEXT);",
+	}, downs)
+	tamperedDownManifest := manifestFor(t, map[string]string{
+		"20240101_1200_init":  files["schema/20240101_1200_init.up.sql"],
+		"20240201_1200_users": files["schema/20240201_1200_users.up.sql"],
+	}, map[string]string{
+		"20240101_1200_init": "DROP TABLE a CASCADE;",
+	})
+	unpinnedDownManifest := manifestFor(t, map[string]string{
+		"20240101_1200_init":  files["schema/20240101_1200_init.up.sql"],
+		"20240201_1200_users": files["schema/20240201_1200_users.up.sql"],
+	}, nil)
+	incompleteManifest := manifestFor(t, map[string]string{
+		"20240101_1200_init": files["schema/20240101_1200_init.up.sql"],
+	}, downs)
+
+	tests := []struct {
+		name    string
+		format  ArchiveFormat
+		files   map[string]string
+		opts    []ArchiveOption
+		wantErr bool
+	}{
+		{"zip", ArchiveZip, files, nil, false},
+		{"tar", ArchiveTar, files, nil, false},
+		{"tar.gz", ArchiveTarGzip, files, nil, false},
+		{"zip with manifest", ArchiveZip, withManifest(validManifest), nil, false},
+		{"tar.gz with manifest", ArchiveTarGzip, withManifest(validManifest), []ArchiveOption{ArchiveRequireManifest()}, false},
+		{"missing required manifest", ArchiveZip, files, []ArchiveOption{ArchiveRequireManifest()}, true},
+		{"tampered migration", ArchiveTar, withManifest(tamperedManifest), nil, true},
+		{"unpinned migration", ArchiveZip, withManifest(incompleteManifest), nil, true},
+		{"tampered down migration", ArchiveTar, withManifest(tamperedDownManifest), nil, true},
+		{"unpinned down migration", ArchiveZip, withManifest(unpinnedDownManifest), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			opts := append([]ArchiveOption{ArchiveDirectory("schema")}, tt.opts...)
+			src := NewArchiveSource(buildArchive(t, tt.format, tt.files), tt.format, opts...)
+			err := src.Init(l)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Init() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			// the archive is read once, so Init can be called again
+			if err = src.Init(l); err != nil {
+				t.Fatalf("second Init() error = %v", err)
+			}
+
+			got, _ := src.ListMigrations()
+			sort.Strings(got)
+			if len(got) != 2 || got[0] != "20240101_1200_init" || got[1] != "20240201_1200_users" {
+				t.Errorf("ListMigrations() got = %v", got)
+			}
+
+			down, err := src.GetParsedDownMigration("20240101_1200_init")
+			if err != nil || down == nil || down.Stmts[0] != "DROP TABLE a;" {
+				t.Errorf("GetParsedDownMigration() got = %v, error = %v", down, err)
+			}
+		})
+	}
+}
@@ -22,7 +22,8 @@ func (a *filesystemSource) Open(name string) (io.ReadCloser, error) {
 }
 
 // NewFilesystemSource provides a new SqlStatementsSource that uses the SQL-files
-// within the passed directory as migrations.
-func NewFilesystemSource(directory string) SqlStatementsSource {
-	return FromFilesystemAdapter(&filesystemSource{}, directory)
+// within the passed directory as migrations. FilesystemOption values can be used
+// to scan subdirectories recursively or ignore certain files.
+func NewFilesystemSource(directory string, opts ...FilesystemOption) SqlStatementsSource {
+	return FromFilesystemAdapter(&filesystemSource{}, directory, opts...)
 }
@@ -24,54 +24,209 @@ type FilesystemAdapter interface {
 	Open(name string) (io.ReadCloser, error)
 }
 
+// FilesystemOption provides configuration values for a SqlStatementsSource
+// created using FromFilesystemAdapter (and therefore also NewFilesystemSource
+// and NewEmbedFSSource).
+type FilesystemOption func(*fsAdapter) error
+
+// FilesystemRecursive instructs the source to walk all subdirectories of the
+// configured directory recursively, so that migrations can be organized in
+// nested folders like "sql/2024/..." or "sql/billing/...". By default, IDs
+// are still derived from the file names only, so they must be unique across
+// all folders. Use FilesystemIDFromPath to include the relative path.
+func FilesystemRecursive() FilesystemOption {
+	return func(src *fsAdapter) error {
+		src.optRecursive = true
+		return nil
+	}
+}
+
+// FilesystemIDFromPath derives migration IDs from the slash-separated path
+// relative to the configured directory (e.g. "billing/20240101_1200_init")
+// instead of the file name only. It is only useful in combination with
+// FilesystemRecursive.
+func FilesystemIDFromPath() FilesystemOption {
+	return func(src *fsAdapter) error {
+		src.optIDFromPath = true
+		return nil
+	}
+}
+
+// FilesystemIgnore skips all files and directories whose name or relative
+// path matches one of the passed patterns. The patterns use the syntax of
+// path.Match. A typical usage would be FilesystemIgnore("README*", ".gitkeep").
+func FilesystemIgnore(patterns ...string) FilesystemOption {
+	return func(src *fsAdapter) error {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("adapt.fsAdapter: invalid ignore pattern %q: %w", pattern, err)
+			}
+		}
+
+		src.optIgnore = append(src.optIgnore, patterns...)
+		return nil
+	}
+}
+
 type fsAdapter struct {
-	log       *slog.Logger
-	adapter   FilesystemAdapter
-	directory string
-	fsMap     map[string]string
-	fsList    []string
+	log         *slog.Logger
+	adapter     FilesystemAdapter
+	directory   string
+	opts        []FilesystemOption
+	fsMap       map[string]string
+	combinedMap map[string]string
+	fsList      []string
+
+	optRecursive  bool
+	optIDFromPath bool
+	optIgnore     []string
 }
 
 func (src *fsAdapter) Init(log *slog.Logger) error {
 	src.log = log
 
-	entries, err := src.adapter.ReadDir(src.directory)
+	// reset state of previous calls, so the same source can be used for
+	// multiple operations (e.g. Migrate followed by Rollback)
+	src.fsMap = make(map[string]string)
+	src.combinedMap = make(map[string]string)
+	src.fsList = nil
+	src.optIgnore = nil
+
+	for _, opt := range src.opts {
+		err := opt(src)
+		if err != nil {
+			log.Error("init failed due to option error", "error", err)
+			return err
+		}
+	}
+
+	filterMap := make(map[string]struct{})
+
+	err := src.scan(src.directory, "", filterMap)
 	if err != nil {
-		log.Error("unable to read directory content", "directory", src.directory, "error", err)
 		return err
 	}
 
-	filterMap := make(map[string]struct{})
+	// a combined file must not be mixed with separate up/down files of the same id
+	for id := range src.combinedMap {
+		_, hasUp := src.fsMap[id+".up"]
+		_, hasDown := src.fsMap[id+".down"]
+		if hasUp || hasDown {
+			log.Error("migration is provided as combined file and as separate up/down files", "migration_id", id)
+			return fmt.Errorf("adapt.fsAdapter: migration provided in multiple formats")
+		}
+	}
+
+	// generate list of map keys
+	for key := range filterMap {
+		src.fsList = append(src.fsList, key)
+	}
+
+	return nil
+}
+
+func (src *fsAdapter) scan(directory string, relative string, filterMap map[string]struct{}) error {
+	entries, err := src.adapter.ReadDir(directory)
+	if err != nil {
+		src.log.Error("unable to read directory content", "directory", directory, "error", err)
+		return err
+	}
 
 	for _, e := range entries {
+		filename := path.Join(directory, e.Name())
+		relativeName := path.Join(relative, e.Name())
+
+		if src.ignored(e.Name(), relativeName) {
+			src.log.Debug("ignoring filesystem entry", "filename", filename)
+			continue
+		}
+
 		if e.IsDir() {
+			if !src.optRecursive {
+				continue
+			}
+			if err = src.scan(filename, relativeName, filterMap); err != nil {
+				return err
+			}
 			continue
 		}
 
 		id := e.Name()
+		if src.optIDFromPath {
+			id = relativeName
+		}
 		id = strings.TrimSuffix(id, ".sql")
 
+		key := id
 		if strings.HasSuffix(id, ".up") {
-			filterMap[strings.TrimSuffix(id, ".up")] = struct{}{}
+			id = strings.TrimSuffix(id, ".up")
 		} else if strings.HasSuffix(id, ".down") {
-			filterMap[strings.TrimSuffix(id, ".down")] = struct{}{}
+			id = strings.TrimSuffix(id, ".down")
 		} else {
-			log.Error("migration with invalid id. Doesn't have '.up.sql' or '.down.sql' suffix",
-				"migration_id", id, "filename", e.Name())
-			return fmt.Errorf("adapt.fsAdapter: migration with invalid id")
+			combined := false
+			if strings.HasSuffix(e.Name(), ".sql") {
+				combined, err = src.isCombined(filename)
+				if err != nil {
+					return err
+				}
+			}
+			switch {
+			case combined:
+			case strings.HasSuffix(e.Name(), ".sql") && strings.HasPrefix(id, RepeatablePrefix):
+				// repeatable migrations are usually re-created completely
+				// by every run and therefore don't need a down migration
+				key = id + ".up"
+			default:
+				src.log.Error("migration with invalid id. Doesn't have '.up.sql' or '.down.sql' suffix and no '-- +adapt Up' marker",
+					"migration_id", id, "filename", e.Name())
+				return fmt.Errorf("adapt.fsAdapter: migration with invalid id")
+			}
 		}
 
-		src.fsMap[id] = path.Join(src.directory, e.Name())
-	}
+		_, existsSeparate := src.fsMap[key]
+		_, existsCombined := src.combinedMap[key]
+		if existsSeparate || existsCombined {
+			src.log.Error("migration file exists multiple times in directory tree", "migration_id", id, "filename", filename)
+			return fmt.Errorf("adapt.fsAdapter: migration with duplicated id")
+		}
 
-	// generate list of map keys
-	for key := range filterMap {
-		src.fsList = append(src.fsList, key)
+		filterMap[id] = struct{}{}
+		if key == id {
+			src.combinedMap[id] = filename
+		} else {
+			src.fsMap[key] = filename
+		}
 	}
 
 	return nil
 }
 
+func (src *fsAdapter) ignored(name string, relativeName string) bool {
+	for _, pattern := range src.optIgnore {
+		// patterns are validated by FilesystemIgnore, therefore errors can be ignored
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, relativeName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (src *fsAdapter) isCombined(filename string) (bool, error) {
+	f, err := src.adapter.Open(filename)
+	if err != nil {
+		src.log.Error("unable to open file", "filename", filename, "error", err)
+		return false, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return hasUpDownMarkers(f)
+}
+
 func (src *fsAdapter) ListMigrations() ([]string, error) {
 	return src.fsList, nil
 }
@@ -89,10 +244,27 @@ func (src *fsAdapter) get(id, filename string) (*ParsedMigration, error) {
 	return Parse(f)
 }
 
+func (src *fsAdapter) getCombined(id, filename string) (up *ParsedMigration, down *ParsedMigration, err error) {
+	f, err := src.adapter.Open(filename)
+	if err != nil {
+		src.log.Error("unable to open file", "id", id, "filename", filename, "error", err)
+		return nil, nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return ParseUpDown(f)
+}
+
 func (src *fsAdapter) GetParsedUpMigration(id string) (*ParsedMigration, error) {
 	if filename, ok := src.fsMap[id+".up"]; ok {
 		return src.get(id, filename)
 	}
+	if filename, ok := src.combinedMap[id]; ok {
+		up, _, err := src.getCombined(id, filename)
+		return up, err
+	}
 
 	return nil, fmt.Errorf("adapt.fsAdapter: unable to find up migration for id %q", id)
 }
@@ -101,16 +273,28 @@ func (src *fsAdapter) GetParsedDownMigration(id string) (*ParsedMigration, error
 	if filename, ok := src.fsMap[id+".down"]; ok {
 		return src.get(id, filename)
 	}
+	if filename, ok := src.combinedMap[id]; ok {
+		_, down, err := src.getCombined(id, filename)
+		return down, err
+	}
 	return nil, nil
 }
 
 // FromFilesystemAdapter converts an FilesystemAdapter implementation to a
 // full-fledged SqlStatementsSource. It unifies the code across most filesystem
 // and the in-memory statements sources.
-func FromFilesystemAdapter(adapter FilesystemAdapter, directory string) SqlStatementsSource {
+//
+// Migrations are either provided as separate "<id>.up.sql" and "<id>.down.sql"
+// files, or as a single "<id>.sql" file that contains both directions separated
+// by "-- +adapt Up" and "-- +adapt Down" markers (see ParseUpDown). Repeatable
+// migrations (see RepeatablePrefix) can also be provided as "<id>.sql" without
+// any markers, in which case the whole file is their Up migration.
+func FromFilesystemAdapter(adapter FilesystemAdapter, directory string, opts ...FilesystemOption) SqlStatementsSource {
 	return &fsAdapter{
-		adapter:   adapter,
-		directory: directory,
-		fsMap:     make(map[string]string),
+		adapter:     adapter,
+		directory:   directory,
+		opts:        opts,
+		fsMap:       make(map[string]string),
+		combinedMap: make(map[string]string),
 	}
 }
@@ -0,0 +1,145 @@
+package adapt
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNewFilesystemSource_Options(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":                         "# migrations",
+		".gitkeep":                          "",
+		"20240101_1200_init.up.sql":         "CREATE TABLE a (id INT);",
+		"2024/20240301_1200_users.up.sql":   "CREATE TABLE users (id INT);",
+		"2024/20240301_1200_users.down.sql": "DROP TABLE users;",
+		"billing/20240201_1200_invoices.sql": "-- +adapt Up\nCREATE TABLE invoices (id INT);\n" +
+			"-- +adapt Down\nDROP TABLE invoices;",
+		"billing/.gitkeep": "",
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    []FilesystemOption
+		want    []string
+		wantErr bool
+	}{
+		{"flat without ignore", nil, nil, true},
+		{"flat with ignore", []FilesystemOption{
+			FilesystemIgnore("README*", ".gitkeep"),
+		}, []string{"20240101_1200_init"}, false},
+		{"recursive", []FilesystemOption{
+			FilesystemRecursive(),
+			FilesystemIgnore("*.md", ".gitkeep"),
+		}, []string{"20240101_1200_init", "20240201_1200_invoices", "20240301_1200_users"}, false},
+		{"recursive with path ids", []FilesystemOption{
+			FilesystemRecursive(),
+			FilesystemIDFromPath(),
+			FilesystemIgnore("*.md", ".gitkeep"),
+		}, []string{"2024/20240301_1200_users", "20240101_1200_init", "billing/20240201_1200_invoices"}, false},
+		{"recursive with ignored directory", []FilesystemOption{
+			FilesystemRecursive(),
+			FilesystemIgnore("*.md", ".gitkeep", "billing"),
+		}, []string{"20240101_1200_init", "20240301_1200_users"}, false},
+		{"invalid pattern", []FilesystemOption{
+			FilesystemIgnore("[-"),
+		}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			src := NewFilesystemSource(dir, tt.opts...)
+			err := src.Init(l)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Init() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			got, _ := src.ListMigrations()
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListMigrations() got = %v, want %v", got, tt.want)
+			}
+
+			for _, id := range got {
+				if _, err := src.GetParsedUpMigration(id); err != nil {
+					t.Errorf("GetParsedUpMigration(%q) error = %v", id, err)
+				}
+			}
+
+			// sources are initialized again when they are reused (e.g. Migrate
+			// followed by Rollback), which must not accumulate previous state
+			if err = src.Init(l); err != nil {
+				t.Fatalf("second Init() error = %v", err)
+			}
+			again, _ := src.ListMigrations()
+			sort.Strings(again)
+			if !reflect.DeepEqual(again, tt.want) {
+				t.Errorf("ListMigrations() after second Init() got = %v, want %v", again, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilesystemSource_Repeatable(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"20240101_1200_init.up.sql": "CREATE TABLE a (id INT);",
+		"R__views.sql":              "CREATE OR REPLACE VIEW v AS SELECT id FROM a;",
+		"R__grants.sql":             "-- +adapt Up\nGRANT SELECT ON a TO reader;\n-- +adapt Down\nREVOKE SELECT ON a FROM reader;",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := NewFilesystemSource(dir)
+	if err := src.Init(slog.New(slog.NewTextHandler(os.Stdout, nil))); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	got, _ := src.ListMigrations()
+	sort.Strings(got)
+	if want := []string{"20240101_1200_init", "R__grants", "R__views"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListMigrations() = %v, want %v", got, want)
+	}
+
+	up, err := src.GetParsedUpMigration("R__views")
+	if err != nil || !reflect.DeepEqual(up.Stmts, []string{"CREATE OR REPLACE VIEW v AS SELECT id FROM a;"}) {
+		t.Errorf("GetParsedUpMigration() = %v, error = %v", up, err)
+	}
+	down, err := src.GetParsedDownMigration("R__views")
+	if err != nil || down != nil {
+		t.Errorf("GetParsedDownMigration() = %v, error = %v, want no down migration", down, err)
+	}
+	down, err = src.GetParsedDownMigration("R__grants")
+	if err != nil || down == nil {
+		t.Errorf("GetParsedDownMigration() = %v, error = %v, want down migration", down, err)
+	}
+
+	// versioned migrations still require a suffix or markers
+	if err = os.WriteFile(filepath.Join(dir, "20240102_1200_users.sql"), []byte("CREATE TABLE users (id INT);"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewFilesystemSource(dir).Init(slog.New(slog.NewTextHandler(os.Stdout, nil))); err == nil {
+		t.Errorf("Init() expected error for versioned migration without suffix or markers")
+	}
+}
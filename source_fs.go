@@ -0,0 +1,40 @@
+package adapt
+
+import (
+	"io"
+	"io/fs"
+)
+
+type fsSource struct {
+	fsys fs.FS
+}
+
+func (a *fsSource) ReadDir(name string) ([]DirEntry, error) {
+	entries, err := fs.ReadDir(a.fsys, name)
+	wrapped := make([]DirEntry, len(entries))
+	for i, e := range entries {
+		wrapped[i] = DirEntry(e)
+	}
+	return wrapped, err
+}
+
+func (a *fsSource) Open(name string) (io.ReadCloser, error) {
+	return a.fsys.Open(name)
+}
+
+// NewFSAdapter provides a FilesystemAdapter for any implementation of Go's
+// standard fs.FS interface.
+func NewFSAdapter(fsys fs.FS) FilesystemAdapter {
+	return &fsSource{fsys}
+}
+
+// NewFSSource provides a new SqlStatementsSource that uses the SQL-files within
+// the passed directory of an fs.FS as migrations. It can be used with every
+// fs.FS implementation, like os.DirFS, fstest.MapFS or zip.Reader. An empty
+// directory is treated as the root of fsys.
+func NewFSSource(fsys fs.FS, directory string, opts ...FilesystemOption) SqlStatementsSource {
+	if directory == "" {
+		directory = "."
+	}
+	return FromFilesystemAdapter(NewFSAdapter(fsys), directory, opts...)
+}
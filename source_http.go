@@ -0,0 +1,259 @@
+package adapt
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// HTTPSourceOption provides configuration values for a SqlStatementsSource
+// created using NewHTTPSource.
+type HTTPSourceOption func(*httpSource) error
+
+// HTTPClient sets the http.Client used to fetch the index and migration files.
+// By default, http.DefaultClient is used.
+func HTTPClient(client *http.Client) HTTPSourceOption {
+	return func(src *httpSource) error {
+		if client == nil {
+			return fmt.Errorf("adapt.httpSource: client cannot be nil")
+		}
+
+		src.client = client
+		return nil
+	}
+}
+
+// HTTPPublicKey sets the ed25519 public key used to verify the signature of
+// the index. It is required, so that a compromised server cannot inject
+// migrations.
+func HTTPPublicKey(key ed25519.PublicKey) HTTPSourceOption {
+	return func(src *httpSource) error {
+		if len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("adapt.httpSource: invalid public key size %d", len(key))
+		}
+
+		src.publicKey = key
+		return nil
+	}
+}
+
+// HTTPIndexName sets the name of the index file relative to the base URL. By
+// default, "index.json" is used. The base64 encoded signature of the index is
+// always expected at the same location with an additional ".sig" suffix.
+func HTTPIndexName(name string) HTTPSourceOption {
+	return func(src *httpSource) error {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			return fmt.Errorf("adapt.httpSource: index name cannot be empty")
+		}
+
+		src.indexName = name
+		return nil
+	}
+}
+
+// HTTPCacheDir sets a local directory in which the index and all migration
+// files are cached after they were fetched successfully. When the server isn't
+// reachable during the next start the cached files are used instead. The
+// signature and hashes of cached content are verified the same way as fetched
+// content.
+func HTTPCacheDir(dir string) HTTPSourceOption {
+	return func(src *httpSource) error {
+		src.cacheDir = dir
+		return nil
+	}
+}
+
+// NewHTTPSource provides a new SqlStatementsSource that fetches its migrations
+// from a remote server. The server must provide an index (a JSON encoded
+// Manifest that lists the Files of every migration) and a base64 encoded
+// ed25519 signature of the index (see SignManifest). Migration files are
+// resolved relative to baseURL and follow the same naming rules as for
+// NewFilesystemSource. The Up and Down part of every fetched migration is
+// verified against the hashes pinned in the signed index (see
+// ManifestEntry.DownHash).
+func NewHTTPSource(baseURL string, opts ...HTTPSourceOption) SqlStatementsSource {
+	return &httpSource{
+		baseURL:   baseURL,
+		opts:      opts,
+		client:    http.DefaultClient,
+		indexName: "index.json",
+	}
+}
+
+type httpSource struct {
+	SqlStatementsSource
+
+	log       *slog.Logger
+	baseURL   string
+	opts      []HTTPSourceOption
+	client    *http.Client
+	publicKey ed25519.PublicKey
+	indexName string
+	cacheDir  string
+}
+
+func (src *httpSource) Init(log *slog.Logger) error {
+	src.log = log
+
+	for _, opt := range src.opts {
+		err := opt(src)
+		if err != nil {
+			log.Error("init failed due to option error", "error", err)
+			return err
+		}
+	}
+
+	if src.publicKey == nil {
+		log.Error("no public key configured to verify the index signature")
+		return fmt.Errorf("adapt.httpSource: public key required")
+	}
+
+	fetched := true
+	files, manifest, err := src.fetchAll()
+	if err != nil {
+		if len(src.cacheDir) == 0 {
+			return err
+		}
+
+		log.Warn("unable to fetch migrations from remote. Falling back to cache", "cache_dir", src.cacheDir, "error", err)
+		fetched = false
+		files, manifest, err = src.loadAll(src.readCache)
+		if err != nil {
+			log.Error("unable to load migrations from cache", "cache_dir", src.cacheDir, "error", err)
+			return err
+		}
+	}
+
+	fsys := make(mapFS)
+	for name, data := range files {
+		if name == src.indexName || name == src.indexName+".sig" {
+			continue
+		}
+		fsys[name] = data
+	}
+
+	src.SqlStatementsSource = NewFSSource(fsys, ".")
+	if err = src.SqlStatementsSource.Init(log); err != nil {
+		return err
+	}
+
+	if err = manifest.verifySource(src.SqlStatementsSource, log); err != nil {
+		return err
+	}
+
+	// only verified content is cached, so that tampered files cannot replace
+	// a previously cached good state
+	if fetched && len(src.cacheDir) > 0 {
+		if err = src.writeCache(files); err != nil {
+			log.Error("unable to write migrations to cache", "cache_dir", src.cacheDir, "error", err)
+			return err
+		}
+	}
+
+	log.Info("verified remote migrations against signed index", "migrations_amount", len(manifest.Migrations))
+	return nil
+}
+
+func (src *httpSource) fetchAll() (map[string][]byte, *Manifest, error) {
+	return src.loadAll(src.fetch)
+}
+
+// loadAll loads the index, verifies its signature and loads all files listed
+// by it using the passed load function.
+func (src *httpSource) loadAll(load func(name string) ([]byte, error)) (map[string][]byte, *Manifest, error) {
+	index, err := load(src.indexName)
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := load(src.indexName + ".sig")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest, err := VerifyManifest(src.publicKey, index, signature)
+	if err != nil {
+		src.log.Error("unable to verify index", "index", src.indexName, "error", err)
+		return nil, nil, err
+	}
+
+	files := map[string][]byte{
+		src.indexName:          index,
+		src.indexName + ".sig": signature,
+	}
+	for _, entry := range manifest.Migrations {
+		if len(entry.Files) == 0 {
+			src.log.Error("index entry doesn't list any files", "migration_id", entry.ID)
+			return nil, nil, ErrInvalidSource
+		}
+
+		for _, name := range entry.Files {
+			if !isLocalName(name) {
+				src.log.Error("index entry contains invalid file name", "migration_id", entry.ID, "filename", name)
+				return nil, nil, ErrInvalidSource
+			}
+
+			data, err := load(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			files[name] = data
+		}
+	}
+
+	return files, manifest, nil
+}
+
+func (src *httpSource) fetch(name string) ([]byte, error) {
+	u, err := url.JoinPath(src.baseURL, name)
+	if err != nil {
+		return nil, err
+	}
+
+	src.log.Debug("fetching remote file", "url", u)
+	resp, err := src.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("adapt.httpSource: unexpected status %q for %q", resp.Status, u)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (src *httpSource) readCache(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(src.cacheDir, path.Base(name)))
+}
+
+func (src *httpSource) writeCache(files map[string][]byte) error {
+	if err := os.MkdirAll(src.cacheDir, 0700); err != nil {
+		return err
+	}
+
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(src.cacheDir, path.Base(name)), data, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isLocalName reports whether name is a plain file name without any path
+// elements, so that it cannot escape the base URL or cache directory.
+func isLocalName(name string) bool {
+	return len(name) > 0 && name != "." && name != ".." && !strings.ContainsAny(name, "/\\")
+}
@@ -0,0 +1,105 @@
+package adapt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewHTTPSource(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"20240101_1200_init.up.sql":   "CREATE TABLE a (id INT);",
+		"20240101_1200_init.down.sql": "DROP TABLE a;",
+	}
+	index, _ := json.Marshal(&Manifest{Migrations: []*ManifestEntry{{
+		ID:       "20240101_1200_init",
+		Hash:     hashOf(t, files["20240101_1200_init.up.sql"]),
+		DownHash: hashOf(t, files["20240101_1200_init.down.sql"]),
+		Files:    []string{"20240101_1200_init.up.sql", "20240101_1200_init.down.sql"},
+	}}})
+	files["index.json"] = string(index)
+	files["index.json.sig"] = string(SignManifest(priv, index))
+
+	online := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/migrations/")]
+		if !online || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	l := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name    string
+		online  bool
+		opts    []HTTPSourceOption
+		wantErr bool
+	}{
+		{"online", true, []HTTPSourceOption{HTTPPublicKey(pub), HTTPCacheDir(cacheDir)}, false},
+		{"offline from cache", false, []HTTPSourceOption{HTTPPublicKey(pub), HTTPCacheDir(cacheDir)}, false},
+		{"offline without cache", false, []HTTPSourceOption{HTTPPublicKey(pub)}, true},
+		{"wrong public key", true, []HTTPSourceOption{HTTPPublicKey(otherPub)}, true},
+		{"missing public key", true, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			online = tt.online
+
+			src := NewHTTPSource(server.URL+"/migrations", tt.opts...)
+			err := src.Init(l)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Init() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			ids, _ := src.ListMigrations()
+			if len(ids) != 1 || ids[0] != "20240101_1200_init" {
+				t.Errorf("ListMigrations() got = %v", ids)
+			}
+			down, err := src.GetParsedDownMigration("20240101_1200_init")
+			if err != nil || down == nil {
+				t.Errorf("GetParsedDownMigration() got = %v, error = %v", down, err)
+			}
+		})
+	}
+
+	for _, name := range []string{"20240101_1200_init.up.sql", "20240101_1200_init.down.sql"} {
+		t.Run("tampered "+name, func(t *testing.T) {
+			online = true
+			original := files[name]
+			files[name] = "DROP TABLE users;"
+			defer func() {
+				files[name] = original
+			}()
+
+			src := NewHTTPSource(server.URL+"/migrations", HTTPPublicKey(pub))
+			if err := src.Init(l); !errors.Is(err, ErrIntegrityProtection) {
+				t.Errorf("Init() error = %v, want ErrIntegrityProtection", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package adapt
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestMapFS(t *testing.T) {
+	fsys := mapFS{
+		"1_a.up.sql":            []byte("CREATE TABLE a (id INT);"),
+		"1_a.down.sql":          []byte("DROP TABLE a;"),
+		"nested/2_b.sql":        []byte("-- +adapt Up\nCREATE TABLE b (id INT);"),
+		"nested/deeper/3_c.sql": []byte("-- +adapt Up\nCREATE TABLE c (id INT);"),
+	}
+	if err := fstest.TestFS(fsys, "1_a.up.sql", "1_a.down.sql", "nested/2_b.sql", "nested/deeper/3_c.sql"); err != nil {
+		t.Fatal(err)
+	}
+}
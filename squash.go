@@ -0,0 +1,197 @@
+package adapt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SquashedMigration is a single migration that consolidates a range of
+// migrations. It is created by Squash.
+type SquashedMigration struct {
+	// ID is the unique identifier of the squashed migration
+	ID string
+	// Replaces lists the IDs of all migrations consolidated by this migration
+	Replaces []string
+	// Up contains the statements of all replaced migrations in order. The
+	// consolidated migration only uses a transaction when all replaced
+	// migrations did.
+	Up *ParsedMigration
+	// Down contains the Down statements of all replaced migrations in reverse
+	// order. It is nil when at least one replaced migration doesn't provide a
+	// Down migration.
+	Down *ParsedMigration
+}
+
+/*
+Squash consolidates all migrations of the SourceCollection up to (and including)
+upToID into a single SquashedMigration with the ID newID. Only migrations from a
+SqlStatementsSource can be squashed.
+
+The squashed migration declares the IDs it replaces (see ParsedMigration.Replaces).
+After the replaced migrations are removed from your sources and the squashed
+migration is added (see SquashedMigration.WriteFile), Migrate treats databases
+that already applied the last replaced migration (upToID) as if they applied
+the squashed migration, while new databases only execute the squashed
+migration. Previously squashed migrations within the range are flattened, so
+databases created before and after an earlier squash are both recognized. The
+range therefore cannot end with a squashed migration.
+
+Example:
+
+	squashed, err := adapt.Squash(
+		adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		},
+		"20231231_2359_last-of-2023",
+		"20240101_0000_baseline-2023",
+	)
+	if err != nil {
+		return err
+	}
+	filename, err := squashed.WriteFile("./sql")
+*/
+func Squash(sources SourceCollection, upToID string, newID string, options ...Option) (*SquashedMigration, error) {
+	e, err := newExec("adapt/squash", nil, sources, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.initSources()
+	if err != nil {
+		return nil, err
+	}
+	err = e.stagePrepareLocal()
+	if err != nil {
+		return nil, err
+	}
+
+	return squashMigrations(e.available, upToID, newID)
+}
+
+func squashMigrations(available []*AvailableMigration, upToID string, newID string) (*SquashedMigration, error) {
+	squashed := &SquashedMigration{
+		ID:   newID,
+		Up:   &ParsedMigration{UseTx: true, Stmts: []string{}},
+		Down: &ParsedMigration{UseTx: true, Stmts: []string{}},
+	}
+
+	for _, am := range available {
+		if am.ID == newID {
+			return nil, fmt.Errorf("adapt: squashed migration id %q is already used", newID)
+		}
+		for _, id := range am.Replaces {
+			if id == newID {
+				return nil, fmt.Errorf("adapt: squashed migration id %q is already used by a migration replaced by %q", newID, am.ID)
+			}
+		}
+	}
+
+	var found bool
+	var downs []*ParsedMigration
+	var dependsOn []string
+	for _, am := range available {
+		src, ok := am.Source.(SqlStatementsSource)
+		if !ok {
+			return nil, fmt.Errorf("adapt: migration %q isn't provided by a SqlStatementsSource and cannot be squashed", am.ID)
+		}
+
+		// flatten previously squashed migrations, so that databases applied
+		// before the first squash are still recognized
+		squashed.Replaces = append(squashed.Replaces, am.Replaces...)
+		squashed.Replaces = append(squashed.Replaces, am.ID)
+		squashed.Up.UseTx = squashed.Up.UseTx && am.ParsedUp.UseTx
+		squashed.Up.AllowDestructive = squashed.Up.AllowDestructive || am.ParsedUp.AllowDestructive
+		squashed.Up.Stmts = append(squashed.Up.Stmts, am.ParsedUp.Stmts...)
+		dependsOn = append(dependsOn, am.DependsOn...)
+
+		down, err := src.GetParsedDownMigration(am.ID)
+		if err != nil {
+			return nil, err
+		}
+		downs = append(downs, down)
+
+		if am.ID == upToID {
+			if len(am.Replaces) > 0 {
+				return nil, fmt.Errorf("adapt: migration %q is a squashed migration and cannot end the squashed range", upToID)
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("adapt: migration %q isn't available", upToID)
+	}
+
+	// keep dependencies on migrations outside the squashed range
+	skip := make(map[string]bool, len(squashed.Replaces))
+	for _, id := range squashed.Replaces {
+		skip[id] = true
+	}
+	for _, dep := range dependsOn {
+		if !skip[dep] {
+			squashed.Up.DependsOn = append(squashed.Up.DependsOn, dep)
+			skip[dep] = true
+		}
+	}
+
+	for idx := len(downs) - 1; idx >= 0; idx-- {
+		if downs[idx] == nil {
+			squashed.Down = nil
+			break
+		}
+		squashed.Down.UseTx = squashed.Down.UseTx && downs[idx].UseTx
+		squashed.Down.Stmts = append(squashed.Down.Stmts, downs[idx].Stmts...)
+	}
+
+	return squashed, nil
+}
+
+// Format renders the SquashedMigration as a combined up/down migration file
+// (see ParseUpDown). Every statement is wrapped in a BeginStatement/EndStatement
+// block, so that it is preserved exactly when the file is parsed again.
+func (s *SquashedMigration) Format() string {
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "-- squashed migration %s\n\n", s.ID)
+	b.WriteString("-- +adapt Up\n")
+	if !s.Up.UseTx {
+		b.WriteString("-- +adapt NoTransaction\n")
+	}
+	if s.Up.AllowDestructive {
+		b.WriteString("-- +adapt AllowDestructive\n")
+	}
+	_, _ = fmt.Fprintf(&b, "-- +adapt Replaces %s\n", strings.Join(s.Replaces, ","))
+	if len(s.Up.DependsOn) > 0 {
+		_, _ = fmt.Fprintf(&b, "-- +adapt DependsOn %s\n", strings.Join(s.Up.DependsOn, ","))
+	}
+	writeStatementBlocks(&b, s.Up.Stmts)
+
+	if s.Down != nil {
+		b.WriteString("\n-- +adapt Down\n")
+		if !s.Down.UseTx {
+			b.WriteString("-- +adapt NoTransaction\n")
+		}
+		writeStatementBlocks(&b, s.Down.Stmts)
+	}
+
+	return b.String()
+}
+
+// WriteFile writes the formatted SquashedMigration to "<dir>/<id>.sql" and
+// returns the filename. It refuses to overwrite existing files.
+func (s *SquashedMigration) WriteFile(dir string) (string, error) {
+	filename := filepath.Join(dir, s.ID+".sql")
+	if err := writeNewFile(filename, s.Format()); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+func writeStatementBlocks(b *strings.Builder, stmts []string) {
+	for _, stmt := range stmts {
+		b.WriteString("\n-- +adapt BeginStatement\n")
+		b.WriteString(stmt)
+		b.WriteString("\n-- +adapt EndStatement\n")
+	}
+}
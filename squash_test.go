@@ -0,0 +1,281 @@
+package adapt
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSquash(t *testing.T) {
+	sources := SourceCollection{
+		NewMemoryFSSource(map[string]string{
+			"20240101_1200_init.up.sql":    "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			"20240101_1200_init.down.sql":  "DROP TABLE b;\nDROP TABLE a;",
+			"20240102_1200_alter.sql":      "-- +adapt Up\nALTER TABLE a ADD c INT;\n-- +adapt Down\nALTER TABLE a DROP c;",
+			"20240103_1200_later.up.sql":   "CREATE TABLE later (id INT);",
+			"20240103_1200_later.down.sql": "DROP TABLE later;",
+		}),
+	}
+
+	squashed, err := Squash(sources, "20240102_1200_alter", "20240102_1300_squashed", DisableLogger())
+	if err != nil {
+		t.Fatalf("Squash() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(squashed.Replaces, []string{"20240101_1200_init", "20240102_1200_alter"}) {
+		t.Errorf("Squash() Replaces = %v", squashed.Replaces)
+	}
+
+	up, down, err := ParseUpDown(strings.NewReader(squashed.Format()))
+	if err != nil {
+		t.Fatalf("ParseUpDown() of formatted squash unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(up, &ParsedMigration{
+		UseTx:    true,
+		Stmts:    []string{"CREATE TABLE a (id INT);", "CREATE TABLE b (id INT);", "ALTER TABLE a ADD c INT;"},
+		Replaces: []string{"20240101_1200_init", "20240102_1200_alter"},
+	}) {
+		t.Errorf("formatted up = %v", up)
+	}
+	if !reflect.DeepEqual(down, &ParsedMigration{
+		UseTx: true,
+		Stmts: []string{"ALTER TABLE a DROP c;", "DROP TABLE b;", "DROP TABLE a;"},
+	}) {
+		t.Errorf("formatted down = %v", down)
+	}
+
+	if _, err = Squash(sources, "20240109_1200_unknown", "20240110_1200_squashed"); err == nil {
+		t.Errorf("Squash() expected error for unknown id")
+	}
+	if _, err = Squash(append(sources, NewCodeSource("20240101_1300_hook", Hook{})), "20240102_1200_alter", "x"); err == nil {
+		t.Errorf("Squash() expected error for hook migration")
+	}
+	if _, err = Squash(sources, "20240102_1200_alter", "20240103_1200_later"); err == nil {
+		t.Errorf("Squash() expected error for new id used after the squashed range")
+	}
+}
+
+// TestSquash_Twice squashes a range containing a previously squashed
+// migration and checks that databases created before and after the first
+// squash are recognized
+func TestSquash_Twice(t *testing.T) {
+	first, err := Squash(SourceCollection{NewMemoryFSSource(map[string]string{
+		"1_a.up.sql": "CREATE TABLE a (id INT);",
+		"2_b.up.sql": "CREATE TABLE b (id INT);",
+		"3_c.up.sql": "CREATE TABLE c (id INT);",
+	})}, "2_b", "2_s1", DisableLogger())
+	if err != nil {
+		t.Fatalf("Squash() first error = %v", err)
+	}
+
+	afterFirst := SourceCollection{NewMemoryFSSource(map[string]string{
+		"2_s1.sql":   first.Format(),
+		"3_c.up.sql": "CREATE TABLE c (id INT);",
+	})}
+	if _, err = Squash(afterFirst, "2_s1", "2_s2", DisableLogger()); err == nil {
+		t.Errorf("Squash() expected error for range ending with a squashed migration")
+	}
+	if _, err = Squash(afterFirst, "3_c", "1_a", DisableLogger()); err == nil {
+		t.Errorf("Squash() expected error for new id of a replaced migration")
+	}
+	second, err := Squash(afterFirst, "3_c", "3_s2", DisableLogger())
+	if err != nil {
+		t.Fatalf("Squash() second error = %v", err)
+	}
+	if want := []string{"1_a", "2_b", "2_s1", "3_c"}; !reflect.DeepEqual(second.Replaces, want) {
+		t.Fatalf("Squash() second Replaces = %v, want %v", second.Replaces, want)
+	}
+
+	hook := func(id string, executed *[]string, replaces ...string) Hook {
+		return Hook{Replaces: replaces, MigrateUp: func() error {
+			*executed = append(*executed, id)
+			return nil
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		before       func(executed *[]string) map[string]Hook
+		wantExecuted []string
+		wantErr      error
+	}{
+		{"created before first squash", func(executed *[]string) map[string]Hook {
+			return map[string]Hook{"1_a": hook("1_a", executed), "2_b": hook("2_b", executed), "3_c": hook("3_c", executed)}
+		}, []string{"4_d"}, nil},
+		{"created after first squash", func(executed *[]string) map[string]Hook {
+			return map[string]Hook{"2_s1": hook("2_s1", executed, first.Replaces...), "3_c": hook("3_c", executed)}
+		}, []string{"4_d"}, nil},
+		{"fresh database", nil, []string{"3_s2", "4_d"}, nil},
+		{"partially applied after first squash", func(executed *[]string) map[string]Hook {
+			return map[string]Hook{"2_s1": hook("2_s1", executed, first.Replaces...)}
+		}, nil, ErrIntegrityProtection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := "test.json"
+			ensureFileIsDeleted(filename)
+			defer ensureFileIsDeleted(filename)
+
+			var executed []string
+			if tt.before != nil {
+				err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(tt.before(&executed))}, DisableLogger())
+				if err != nil {
+					t.Fatal(err)
+				}
+				executed = nil
+			}
+
+			err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{
+				NewCodePackageSource(map[string]Hook{
+					"3_s2": hook("3_s2", &executed, second.Replaces...),
+					"4_d":  hook("4_d", &executed),
+				}),
+			}, DisableLogger())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(executed, tt.wantExecuted) {
+				t.Errorf("executed = %v, want %v", executed, tt.wantExecuted)
+			}
+		})
+	}
+}
+
+func TestMigrate_Replaces(t *testing.T) {
+	hook := func(id string, executed *[]string) Hook {
+		return Hook{MigrateUp: func() error {
+			*executed = append(*executed, id)
+			return nil
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		applied      []string
+		wantExecuted []string
+		wantErr      error
+	}{
+		{"squashed range applied", []string{"1_a", "2_b"}, []string{"4_c"}, nil},
+		{"fresh database", nil, []string{"3_squashed", "4_c"}, nil},
+		{"partially applied", []string{"1_a"}, nil, ErrIntegrityProtection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := "test.json"
+			ensureFileIsDeleted(filename)
+			defer ensureFileIsDeleted(filename)
+
+			var executed []string
+			if len(tt.applied) > 0 {
+				original := make(map[string]Hook)
+				for _, id := range tt.applied {
+					original[id] = hook(id, &executed)
+				}
+				err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(original)}, DisableLogger())
+				if err != nil {
+					t.Fatal(err)
+				}
+				executed = nil
+			}
+
+			squashed := hook("3_squashed", &executed)
+			squashed.Replaces = []string{"1_a", "2_b"}
+			err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{
+				NewCodePackageSource(map[string]Hook{
+					"3_squashed": squashed,
+					"4_c":        hook("4_c", &executed),
+				}),
+			}, DisableLogger())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(executed, tt.wantExecuted) {
+				t.Errorf("executed = %v, want %v", executed, tt.wantExecuted)
+			}
+		})
+	}
+}
+
+func TestPlan_Replaces(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	noop := Hook{MigrateUp: func() error { return nil }}
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_a": noop,
+		"2_b": noop,
+	})}, DisableLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	squashed := noop
+	squashed.Replaces = []string{"1_a", "2_b"}
+	plan, err := Plan(NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"3_squashed": squashed,
+		"4_c":        noop,
+	})}, DisableLogger())
+	if err != nil {
+		t.Fatalf("Plan() unexpected error = %v", err)
+	}
+
+	var apply []string
+	for _, am := range plan.Apply {
+		apply = append(apply, am.ID)
+	}
+	if len(plan.Rollback) > 0 || !reflect.DeepEqual(apply, []string{"4_c"}) {
+		t.Errorf("Plan() rollback = %v, apply = %v, want apply [4_c]", plan.Rollback, apply)
+	}
+}
+
+func TestStatus_Replaces(t *testing.T) {
+	filename := "test.json"
+	ensureFileIsDeleted(filename)
+	defer ensureFileIsDeleted(filename)
+
+	noop := Hook{MigrateUp: func() error { return nil }}
+	err := Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_a": noop,
+		"2_b": noop,
+	})}, DisableLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	squashed := noop
+	squashed.Replaces = []string{"1_a", "2_b"}
+	statuses, err := Status(NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"3_squashed": squashed,
+		"4_c":        noop,
+	})}, DisableLogger())
+	if err != nil {
+		t.Fatalf("Status() unexpected error = %v", err)
+	}
+
+	var got []string
+	for _, s := range statuses {
+		got = append(got, s.ID+"="+string(s.State))
+		if (s.Available == nil) != (s.State == StatusReplaced) {
+			t.Errorf("Status() %s has unexpected Available field", s.ID)
+		}
+	}
+	if want := []string{"1_a=replaced", "2_b=replaced", "3_squashed=applied", "4_c=pending"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Status() = %v, want %v", got, want)
+	}
+
+	// partially applied squashed ranges are refused like Migrate does
+	ensureFileIsDeleted(filename)
+	err = Migrate("adapt-tester@v1.1.7", NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"1_a": noop,
+	})}, DisableLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Status(NewFileDriver(filename), SourceCollection{NewCodePackageSource(map[string]Hook{
+		"3_squashed": squashed,
+	})}, DisableLogger())
+	if !errors.Is(err, ErrIntegrityProtection) {
+		t.Errorf("Status() error = %v, want ErrIntegrityProtection", err)
+	}
+}
@@ -0,0 +1,82 @@
+package adapt
+
+// StatusState describes the state of a single migration reported by Status.
+type StatusState string
+
+const (
+	// StatusApplied is reported for migrations that are available and
+	// applied. Migrations that Migrate would record as applied without
+	// executing them (see BaselineOnEmptyMeta and Squash) are reported as
+	// applied as well.
+	StatusApplied StatusState = "applied"
+	// StatusPending is reported for available migrations that aren't applied
+	// yet and would be applied by Migrate. Repeatable migrations are also
+	// pending when their content changed since their last run.
+	StatusPending StatusState = "pending"
+	// StatusUnknown is reported for applied migrations that aren't provided by
+	// any source. Migrate would roll them back.
+	StatusUnknown StatusState = "unknown"
+	// StatusSkipped is reported for migrations that aren't applied, because
+	// they are filtered by IncludeTags or ExcludeTags.
+	StatusSkipped StatusState = "skipped"
+	// StatusReplaced is reported for applied migrations that aren't provided
+	// by any source anymore, because they were consolidated into a squashed
+	// migration (see Squash). Migrate neither rolls them back nor applies them
+	// again.
+	StatusReplaced StatusState = "replaced"
+)
+
+// MigrationStatus reports the state of a single migration.
+type MigrationStatus struct {
+	// ID is the unique identifier of the migration
+	ID string
+	// State is the current StatusState of the migration
+	State StatusState
+	// Available is the local migration. It is nil for StatusUnknown and
+	// StatusReplaced.
+	Available *AvailableMigration
+	// Applied is the migration stored by the Driver. It is nil for
+	// StatusPending and StatusSkipped, except for repeatable migrations that
+	// were applied before. It is also nil for applied migrations that Migrate
+	// would record without executing them.
+	Applied *Migration
+}
+
+/*
+Status reports the state of all migrations known to the SourceCollection or the
+Driver without changing anything. Migrations are ordered by their ID using the
+configured Comparator (see OrderBy). Applied migrations that are filtered by
+their tags are reported as StatusApplied.
+
+Example:
+
+	statuses, err := adapt.Status(
+		adapt.NewPostgresDriver(db),
+		adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		},
+		adapt.ExcludeTags("seed"),
+	)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		fmt.Println(s.ID, s.State)
+	}
+*/
+func Status(driver Driver, sources SourceCollection, options ...Option) ([]*MigrationStatus, error) {
+	e, err := newExec("adapt/status", driver, sources, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*MigrationStatus
+	err = e.runWith(func() error {
+		statuses, err = e.stageStatus()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
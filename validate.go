@@ -0,0 +1,144 @@
+package adapt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity classifies a Diagnostic reported by Validate
+type Severity string
+
+const (
+	// SeverityError marks problems that make Migrate fail or misbehave
+	SeverityError Severity = "error"
+	// SeverityWarning marks suspicious migrations that are still applicable
+	SeverityWarning Severity = "warning"
+)
+
+// DiagnosticCode identifies the kind of problem a Diagnostic reports
+type DiagnosticCode string
+
+const (
+	// DiagnosticSource reports a Source failing to initialize or list its
+	// migrations
+	DiagnosticSource DiagnosticCode = "source"
+	// DiagnosticParse reports a migration that couldn't be parsed
+	DiagnosticParse DiagnosticCode = "parse"
+	// DiagnosticDuplicateID reports a migration ID provided by multiple sources
+	DiagnosticDuplicateID DiagnosticCode = "duplicate_id"
+	// DiagnosticInvalidID reports a migration ID rejected by an IDValidator (see
+	// ValidateIDs)
+	DiagnosticInvalidID DiagnosticCode = "invalid_id"
+	// DiagnosticDependency reports dependency cycles and dependencies on
+	// unknown migrations (see ParsedMigration.DependsOn)
+	DiagnosticDependency DiagnosticCode = "dependency"
+	// DiagnosticMissingDown reports a migration without a down migration
+	DiagnosticMissingDown DiagnosticCode = "missing_down"
+	// DiagnosticEmptyMigration reports a migration without statements or a
+	// Hook without a migrate function
+	DiagnosticEmptyMigration DiagnosticCode = "empty_migration"
+	// DiagnosticEmptyStatement reports a statement without any SQL
+	DiagnosticEmptyStatement DiagnosticCode = "empty_statement"
+	// DiagnosticNoTransaction reports a NoTransaction migration with multiple
+	// statements, which can be left partially applied
+	DiagnosticNoTransaction DiagnosticCode = "no_transaction"
+	// DiagnosticDialectHazard reports a statement that misbehaves inside a
+	// transaction in the configured Dialect (see SQLDialect)
+	DiagnosticDialectHazard DiagnosticCode = "dialect_hazard"
+	// DiagnosticPolicy reports an unacknowledged statement violating the
+	// policy (see EnforcePolicy)
+	DiagnosticPolicy DiagnosticCode = "policy"
+)
+
+// Diagnostic is a single problem found by Validate
+type Diagnostic struct {
+	Severity    Severity       `json:"severity"`
+	Code        DiagnosticCode `json:"code"`
+	MigrationID string         `json:"migration_id,omitempty"`
+	Message     string         `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if len(d.MigrationID) == 0 {
+		return fmt.Sprintf("%s [%s]: %s", d.Severity, d.Code, d.Message)
+	}
+	return fmt.Sprintf("%s %s [%s]: %s", d.Severity, d.MigrationID, d.Code, d.Message)
+}
+
+// Diagnostics is a list of Diagnostic elements
+type Diagnostics []Diagnostic
+
+// Errors returns all Diagnostic elements with SeverityError
+func (d Diagnostics) Errors() Diagnostics {
+	var errs Diagnostics
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			errs = append(errs, diag)
+		}
+	}
+	return errs
+}
+
+// Err returns an error wrapping ErrInvalidSource that lists all Diagnostic
+// elements with SeverityError, or nil when there are none.
+func (d Diagnostics) Err() error {
+	errs := d.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, diag := range errs {
+		msgs = append(msgs, diag.String())
+	}
+	return fmt.Errorf("adapt: validation found %d error(s): %s: %w", len(errs), strings.Join(msgs, "; "), ErrInvalidSource)
+}
+
+func (d *Diagnostics) add(severity Severity, code DiagnosticCode, migrationID string, format string, args ...interface{}) {
+	*d = append(*d, Diagnostic{
+		Severity:    severity,
+		Code:        code,
+		MigrationID: migrationID,
+		Message:     fmt.Sprintf(format, args...),
+	})
+}
+
+/*
+Validate checks the SourceCollection without connecting to a Driver and
+returns all problems found as Diagnostics. Unlike Migrate it doesn't stop at the
+first problem: every source is initialized and every migration (including ones
+filtered by IncludeTags or ExcludeTags) is parsed and checked for:
+
+  - duplicate IDs across sources
+  - IDs rejected by the configured validators (see ValidateIDs)
+  - dependency cycles and dependencies on unknown migrations
+  - missing down migrations (except for repeatable migrations)
+  - migrations without statements and empty statements
+  - NoTransaction migrations with multiple statements
+  - statements that misbehave inside a transaction, like
+    "CREATE INDEX CONCURRENTLY" on PostgreSQL, when a Dialect is configured
+    using SQLDialect
+  - up migrations violating the policy, when configured using EnforcePolicy
+
+The returned error wraps ErrInvalidSource when at least one Diagnostic has
+SeverityError (see Diagnostics.Err). Warnings alone don't cause an error.
+
+Example:
+
+	diagnostics, err := adapt.Validate(
+		adapt.SourceCollection{
+			adapt.NewFilesystemSource("./sql"),
+		},
+		adapt.ValidateIDs(adapt.ValidTimestampID),
+		adapt.SQLDialect(adapt.DialectPostgres),
+	)
+*/
+func Validate(sources SourceCollection, options ...Option) (Diagnostics, error) {
+	e, err := newExec("adapt/validate", nil, sources, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := e.stageValidate()
+	return diagnostics, diagnostics.Err()
+}
@@ -0,0 +1,185 @@
+package adapt
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	up := func(tx *sql.Tx) error { return nil }
+
+	type diag struct {
+		severity Severity
+		code     DiagnosticCode
+		id       string
+	}
+	tests := []struct {
+		name    string
+		sources SourceCollection
+		options []Option
+		want    []diag
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_init.up.sql":   "CREATE TABLE a (id INT);",
+				"20240101_1200_init.down.sql": "DROP TABLE a;",
+			})},
+			options: []Option{ValidateIDs(ValidTimestampID), SQLDialect(DialectPostgres)},
+		},
+		{
+			name: "duplicate ids and missing downs",
+			sources: SourceCollection{
+				NewMemoryFSSource(map[string]string{
+					"20240101_1200_init.up.sql": "CREATE TABLE a (id INT);",
+				}),
+				NewCodeSource("20240101_1200_init", Hook{MigrateUpTx: up}),
+				NewCodeSource("20240102_1200_hook", Hook{MigrateUpTx: up}),
+				NewCodeSource("20240103_1200_empty", Hook{}),
+			},
+			want: []diag{
+				{SeverityError, DiagnosticDuplicateID, "20240101_1200_init"},
+				{SeverityWarning, DiagnosticMissingDown, "20240101_1200_init"},
+				{SeverityWarning, DiagnosticMissingDown, "20240102_1200_hook"},
+				{SeverityError, DiagnosticEmptyMigration, "20240103_1200_empty"},
+				{SeverityWarning, DiagnosticMissingDown, "20240103_1200_empty"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid ids",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"init.up.sql":   "CREATE TABLE a (id INT);",
+				"init.down.sql": "DROP TABLE a;",
+			})},
+			options: []Option{ValidateIDs(ValidTimestampID)},
+			want:    []diag{{SeverityError, DiagnosticInvalidID, "init"}},
+			wantErr: true,
+		},
+		{
+			name: "parse errors",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_init.up.sql":   "CREATE TABLE a (id INT);\n-- +adapt NoTransaction\n",
+				"20240101_1200_init.down.sql": "DROP TABLE a;",
+				"20240102_1200_b.up.sql":      "CREATE TABLE b (id INT);",
+				"20240102_1200_b.down.sql":    "DROP TABLE b;\n-- +adapt NoTransaction\n",
+			})},
+			want: []diag{
+				{SeverityError, DiagnosticParse, "20240101_1200_init"},
+				{SeverityError, DiagnosticParse, "20240102_1200_b"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty migrations and statements",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_init.up.sql":   "-- nothing yet\n",
+				"20240101_1200_init.down.sql": "DROP TABLE a;\n;",
+			})},
+			want: []diag{
+				{SeverityWarning, DiagnosticEmptyMigration, "20240101_1200_init"},
+				{SeverityError, DiagnosticEmptyStatement, "20240101_1200_init"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "NoTransaction with multiple statements",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_init.up.sql":   "-- +adapt NoTransaction\nCREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+				"20240101_1200_init.down.sql": "-- +adapt NoTransaction\nDROP TABLE a;",
+			})},
+			want: []diag{{SeverityWarning, DiagnosticNoTransaction, "20240101_1200_init"}},
+		},
+		{
+			name: "postgres hazards",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_idx.up.sql":      "CREATE UNIQUE INDEX CONCURRENTLY idx ON a (id);",
+				"20240101_1200_idx.down.sql":    "-- +adapt NoTransaction\nDROP INDEX CONCURRENTLY idx;",
+				"20240102_1200_vacuum.up.sql":   "VACUUM a;",
+				"20240102_1200_vacuum.down.sql": "SELECT 1;",
+			})},
+			options: []Option{SQLDialect(DialectPostgres)},
+			want: []diag{
+				{SeverityError, DiagnosticDialectHazard, "20240101_1200_idx"},
+				{SeverityError, DiagnosticDialectHazard, "20240102_1200_vacuum"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hazards are dialect specific",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_idx.up.sql":   "CREATE INDEX CONCURRENTLY idx ON a (id);",
+				"20240101_1200_idx.down.sql": "DROP INDEX idx;",
+			})},
+			options: []Option{SQLDialect(DialectMySQL)},
+		},
+		{
+			name: "mysql implicit commit",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_init.up.sql":   "CREATE TABLE a (id INT);\nINSERT INTO a (id) VALUES (1);",
+				"20240101_1200_init.down.sql": "DROP TABLE a;",
+			})},
+			options: []Option{SQLDialect(DialectMySQL)},
+			want:    []diag{{SeverityWarning, DiagnosticDialectHazard, "20240101_1200_init"}},
+		},
+		{
+			name: "policy",
+			sources: SourceCollection{NewMemoryFSSource(map[string]string{
+				"20240101_1200_drop.up.sql":    "DROP TABLE a;",
+				"20240101_1200_drop.down.sql":  "CREATE TABLE a (id INT);",
+				"20240102_1200_ack.up.sql":     "-- +adapt AllowDestructive\nDROP TABLE b;",
+				"20240102_1200_ack.down.sql":   "CREATE TABLE b (id INT);",
+				"20240103_1200_index.up.sql":   "CREATE INDEX idx ON c (id);",
+				"20240103_1200_index.down.sql": "DROP INDEX idx;",
+			})},
+			options: []Option{EnforcePolicy(DefaultPolicyRules()...), SQLDialect(DialectPostgres)},
+			want: []diag{
+				{SeverityError, DiagnosticPolicy, "20240101_1200_drop"},
+				{SeverityError, DiagnosticPolicy, "20240103_1200_index"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dependencies",
+			sources: SourceCollection{
+				NewCodeSource("20240101_1200_a", Hook{MigrateUpTx: up, DependsOn: []string{"20240102_1200_b"}, Repeatable: true}),
+				NewCodeSource("20240102_1200_b", Hook{MigrateUpTx: up, DependsOn: []string{"20240101_1200_a", "20230101_1200_gone"}, Repeatable: true}),
+			},
+			want: []diag{
+				{SeverityWarning, DiagnosticDependency, "20240102_1200_b"},
+				{SeverityError, DiagnosticDependency, ""},
+			},
+			wantErr: true,
+		},
+		{
+			name: "self dependency",
+			sources: SourceCollection{
+				NewCodeSource("20240101_1200_a", Hook{MigrateUpTx: up, DependsOn: []string{"20240101_1200_a"}, Repeatable: true}),
+			},
+			want:    []diag{{SeverityError, DiagnosticDependency, "20240101_1200_a"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(tt.sources, append(tt.options, DisableLogger())...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v (diagnostics: %v)", err, tt.wantErr, got)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSource) {
+				t.Errorf("Validate() error = %v, want ErrInvalidSource", err)
+			}
+
+			var gotDiags []diag
+			for _, d := range got {
+				gotDiags = append(gotDiags, diag{d.Severity, d.Code, d.MigrationID})
+			}
+			if !reflect.DeepEqual(gotDiags, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}